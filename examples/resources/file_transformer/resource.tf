@@ -0,0 +1,11 @@
+resource "file_transformer" "name" {
+  file                 = "./docker-compose.yml"
+  override_array_items = false
+  items = jsonencode(
+    {
+      "my-container" = {
+        environment = ["NODE_ENV=production"]
+      }
+    }
+  )
+}
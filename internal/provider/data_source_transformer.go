@@ -2,73 +2,250 @@ package provider
 
 import (
 	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
-	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/hashicorp/terraform-provider-scaffolding/utils"
 )
 
+var (
+	supportedOutputFileExt = []string{".json", ".env", ".yaml", ".yml", ".toml", ".xml",
+		".ini", ".cfg", ".gitconfig", ".service", ".socket", ".timer", ".mount", ".target", ".path", ".properties",
+		".tfvars", ".hcl"}
+	supportedFileExt = supportedOutputFileExt
+)
+
 func dataSourceTransformer() *schema.Resource {
 	return &schema.Resource{
-		ReadContext: resourceTransformerRead,
+		ReadContext: dataSourceTransformerRead,
 		Description: "The `file_transformer` data source provides an interface between terraform " +
 			"and the file manager of the machine that is running terraform, allowing to overwrite, delete/edit file contents. " +
 			"The `file_transformer` data source can be used with existing or non-existing files, " +
-			"currently supported file extensions are json, .env and yaml (or yml)" +
+			"currently supported file extensions are json, .env, yaml (or yml), toml, xml, ini (.ini, .cfg, .gitconfig and systemd unit files), properties and hcl (.tfvars and .hcl)" +
 			"\n" +
 			"**Warning** It is necessary that you grant sufficient permissions so that the provider can read " +
 			"and make changes to the contents of the specified file (_chmod +rw_). If the file does not exist, the `file` provider " +
 			"will try to create a new file or folder (in this case the file must be placed in the subfolder that does not exist), " +
 			"so the permissions must also cover these situations.",
-		Schema: map[string]*schema.Schema{
-			"file": &schema.Schema{
-				Description: "(Required) Source file, the content provided in `items` field is merged with the content of this file. If  " +
-					"`output` property is empty, the merge result will be saved in the given file. Currently supported file " +
-					"extensions are _json, .env and yaml (or yml)_. When the file extension is _.env_ only _file_ and _items_ properties are " +
-					"taken into account (so filling in the other properties has no effect)",
-				Required:     true,
-				Type:         schema.TypeString,
-				ValidateFunc: validateFileExt([]string{".json", ".yaml", ".yml", ".env"}),
-			},
-			"output": &schema.Schema{
-				Description:  "(Optional) Destination file. Defaults to the value of `file` property.",
-				Optional:     true,
-				Type:         schema.TypeString,
-				ValidateFunc: validateFileExt([]string{".json", ".yaml", ".yml"}),
-			},
-			"override_array_items": &schema.Schema{
-				Description: "(Optional) In situations where the object defined in the `items` field contains a _Key_ whose " +
-					"associated value is array and the same _Key_ exists (on the same level) in the specified file, if this property " +
-					"is false then the key values (defined in the `items` field and specified file) will be merged, on the other hand " +
-					"if this property is set to true, then the value associated with the same _Key_ in the selected file will be replaced " +
-					"by the value (associated with the _Key_) defined in the `items` field. This setting is only applicable to json and yaml files. " +
-					"Defaults to `true`",
-				Optional: true,
-				Default:  true,
-				Type:     schema.TypeBool,
-			},
-			"items": &schema.Schema{
-				Description: "Content to be placed in the file, it's necessary to encode items using JSON syntax " +
-					"(only when file extension is json or yaml), thus we advise to use the terraform built-in function " +
-					"[`jsonencode`](https://developer.hashicorp.com/terraform/language/functions/jsonencode) to assign any value to this property. ",
-				Required: true,
-				Type:     schema.TypeString,
+		Schema: transformerSchema(),
+	}
+}
+
+// transformerSchema returns the attributes shared by the `file_transformer` data source and resource, the
+// resource changes the ones that force a new resource and adds the changes it keeps in the state
+func transformerSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"file": &schema.Schema{
+			Description: "(Required) Source file, the content provided in `items` field is merged with the content of this file. If  " +
+				"`output` property is empty, the merge result will be saved in the given file. Currently supported file " +
+				"extensions are _json, .env, yaml (or yml), toml, xml, ini (.ini, .cfg, .gitconfig and systemd unit files), properties and hcl (.tfvars and .hcl)_.",
+			Required:     true,
+			Type:         schema.TypeString,
+			ValidateFunc: validateFileExt(supportedFileExt),
+		},
+		"output": &schema.Schema{
+			Description:  "(Optional) Destination file. Defaults to the value of `file` property.",
+			Optional:     true,
+			Type:         schema.TypeString,
+			ValidateFunc: validateFileExt(supportedOutputFileExt),
+		},
+		"override_array_items": &schema.Schema{
+			Description: "(Optional) When set to true, arrays defined in `items` replace the arrays with the same _Key_ " +
+				"in the file, otherwise both arrays are joined. This setting is only applicable to json, yaml, toml, xml and ini files. " +
+				"Defaults to the provider `override_array_items` (`true` unless it is changed)",
+			Optional: true,
+			Computed: true,
+			Type:     schema.TypeBool,
+		},
+		"array_merge_strategy": &schema.Schema{
+			Description: "(Optional) How the arrays defined in `items` are merged with the arrays with the same _Key_ in the file: " +
+				"`replace` replaces them, `append` joins both arrays, `union` joins them as sets, so elements already present " +
+				"in the file (compared by deep equality) are not added again and `index` deep merges each element into the element " +
+				"of the file placed in the same position, appending the extra elements. When it's not set, `override_array_items` decides " +
+				"between `replace` and `append`.",
+			Optional:     true,
+			Type:         schema.TypeString,
+			ValidateFunc: validation.StringInSlice([]string{utils.ArrayReplace, utils.ArrayAppend, utils.ArrayUnion, utils.ArrayIndex}, false),
+		},
+		"array_merge_keys": &schema.Schema{
+			Description: "(Optional) Field identifying the elements of the arrays of objects placed in the given paths, e.g. " +
+				"`{ \"spec.containers\" = \"name\" }`. Elements of `items` are deep merged into the element of the file with the same " +
+				"value of the field and new elements are appended, whatever `override_array_items` is. Paths are the keys from the root " +
+				"of the file joined with dots, the elements of an array have the path of the array (e.g. `spec.containers.ports`).",
+			Optional: true,
+			Type:     schema.TypeMap,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		},
+		"merge_rules": &schema.Schema{
+			Description: "(Optional) Rules assigning a merge strategy to the values whose path matches a selector, the first " +
+				"rule matching a path is used and rules take precedence over `override_array_items`, `array_merge_strategy` and `array_merge_keys`.",
+			Optional: true,
+			Type:     schema.TypeList,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"path": &schema.Schema{
+						Description: "Selector of the values, keys from the root of the file joined with dots where `*` matches " +
+							"any key (e.g. `services.*.environment`). A leading `$.` and `[*]` are allowed, the elements of an array " +
+							"have the path of the array.",
+						Required: true,
+						Type:     schema.TypeString,
+					},
+					"strategy": &schema.Schema{
+						Description: "Strategy of the values: `override` replaces them, `keep-existing` keeps the values of the file " +
+							"(values missing in the file are set), `append`, `union`, `index` and `by-key` merge arrays as described " +
+							"in `array_merge_strategy` and `array_merge_keys`.",
+						Required: true,
+						Type:     schema.TypeString,
+						ValidateFunc: validation.StringInSlice([]string{
+							utils.RuleOverride, utils.RuleKeepExisting, utils.ArrayAppend, utils.ArrayUnion, utils.ArrayIndex, utils.ArrayByKey,
+						}, false),
+					},
+					"key": &schema.Schema{
+						Description: "Field identifying the elements of the arrays, required by the `by-key` strategy.",
+						Optional:    true,
+						Type:        schema.TypeString,
+					},
+				},
 			},
 		},
+		"merge_strategy": &schema.Schema{
+			Description: "(Optional) How `items` are merged into the file: `deep_merge` adds and overwrites keys, `merge_patch` " +
+				"applies `items` as a JSON Merge Patch (RFC 7396), so keys set to `null` are removed from the file and arrays are " +
+				"replaced. Defaults to `deep_merge`",
+			Optional:     true,
+			Default:      utils.DeepMergeStrategy,
+			Type:         schema.TypeString,
+			ValidateFunc: validation.StringInSlice([]string{utils.DeepMergeStrategy, utils.MergePatchStrategy}, false),
+		},
+		"key_separator": &schema.Schema{
+			Description: "(Optional) Separator used to join nested keys when json, yaml (or any other nested format) " +
+				"content is written to a .env file (e.g. `{\"db\":{\"host\":\"localhost\"}}` is written as `db__host=localhost`) " +
+				"and to split the variables when a .env file is written to a nested format. Defaults to `__`",
+			Optional: true,
+			Default:  "__",
+			Type:     schema.TypeString,
+		},
+		"key_prefix": &schema.Schema{
+			Description: "(Optional) Prefix added to the variables written to a .env file from a nested format, when a .env file is " +
+				"written to a nested format only the variables with this prefix are taken into account (and the prefix is removed).",
+			Optional: true,
+			Default:  "",
+			Type:     schema.TypeString,
+		},
+		"file_permission": &schema.Schema{
+			Description: "(Optional) Permissions of the `output` file in octal notation (e.g. `0600`). When it's not set, " +
+				"existing files keep their permissions and new files are created with the permissions configured in the provider.",
+			Optional:     true,
+			Type:         schema.TypeString,
+			ValidateFunc: validatePermission,
+		},
+		"directory_permission": &schema.Schema{
+			Description: "(Optional) Permissions of the directories created to hold the `output` file in octal notation " +
+				"(e.g. `0700`). Defaults to the permissions configured in the provider.",
+			Optional:     true,
+			Type:         schema.TypeString,
+			ValidateFunc: validatePermission,
+		},
+		"owner": &schema.Schema{
+			Description: "(Optional) User that owns the `output` file, either a name or a numeric id. When it's not set, " +
+				"existing files keep their owner if terraform is allowed to give them away (only privileged users are).",
+			Optional: true,
+			Type:     schema.TypeString,
+		},
+		"group": &schema.Schema{
+			Description: "(Optional) Group that owns the `output` file, either a name or a numeric id. When it's not set, " +
+				"existing files keep their group if the user running terraform is allowed to (e.g. a member of the group).",
+			Optional: true,
+			Type:     schema.TypeString,
+		},
+		"backup": &schema.Schema{
+			Description: "(Optional) When set to true, the previous content of the `output` file is saved before it's overwritten. " +
+				"Backups are named after the file followed by the time they were taken and the `.bak` extension " +
+				"(e.g. `config.json.20230102T150405.000000000.bak`), they are placed next to the file unless the " +
+				"provider `backup_dir` is set. Defaults to the provider `backup` (`false` unless it is changed)",
+			Optional: true,
+			Computed: true,
+			Type:     schema.TypeBool,
+		},
+		"backup_retention": &schema.Schema{
+			Description: "(Optional) Number of backups of the `output` file to be kept, the oldest ones are removed. Set it to 0 to keep all of them. " +
+				"Defaults to the provider `backup_retention` (`5` unless it is changed)",
+			Optional:     true,
+			Computed:     true,
+			Type:         schema.TypeInt,
+			ValidateFunc: validation.IntAtLeast(0),
+		},
+		"indent": &schema.Schema{
+			Description: "(Optional) Number of spaces used to indent json and yaml files written from scratch, existing files keep " +
+				"their indentation. Defaults to the provider `indent`",
+			Optional:     true,
+			Computed:     true,
+			Type:         schema.TypeInt,
+			ValidateFunc: validation.IntAtLeast(0),
+		},
+		"items": &schema.Schema{
+			Description: "Content to be placed in the file, it's necessary to encode items using JSON syntax " +
+				"(only when file extension is json or yaml), thus we advise to use the terraform built-in function " +
+				"[`jsonencode`](https://developer.hashicorp.com/terraform/language/functions/jsonencode) to assign any value to this property. ",
+			Optional:     true,
+			AtLeastOneOf: []string{"items", "patch"},
+			Type:         schema.TypeString,
+		},
+		"patch": &schema.Schema{
+			Description: "(Optional) JSON Patch ([RFC 6902](https://www.rfc-editor.org/rfc/rfc6902)) applied to the file once `items` are merged, " +
+				"that is a JSON encoded list of `add`, `remove`, `replace`, `move`, `copy` and `test` operations. When a `test` " +
+				"operation does not match, the file is left untouched and the apply fails. The patch is applied on every refresh, so operations " +
+				"that aren't idempotent (`add` at an array index or at `-`, `move` and `copy`) are repeated. Only applicable to json, yaml, " +
+				"toml, ini, xml and hcl files.",
+			Optional:     true,
+			Type:         schema.TypeString,
+			ValidateFunc: validation.StringIsJSON,
+			AtLeastOneOf: []string{"items", "patch"},
+		},
+		"backup_path": &schema.Schema{
+			Description: "Path of the most recent backup of the `output` file, empty when `backup` is false or the file was never overwritten.",
+			Computed:    true,
+			Type:        schema.TypeString,
+		},
+		"content": &schema.Schema{
+			Description: "Content of the `output` file after the transformation.",
+			Computed:    true,
+			Type:        schema.TypeString,
+		},
+		"content_base64": &schema.Schema{
+			Description: "Base64 encoded content of the `output` file after the transformation.",
+			Computed:    true,
+			Type:        schema.TypeString,
+		},
+		"content_sha256": &schema.Schema{
+			Description: "SHA256 checksum of the `output` file content after the transformation.",
+			Computed:    true,
+			Type:        schema.TypeString,
+		},
+		"content_md5": &schema.Schema{
+			Description: "MD5 checksum of the `output` file content after the transformation.",
+			Computed:    true,
+			Type:        schema.TypeString,
+		},
 	}
 }
 
-func resourceTransformerRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+func dataSourceTransformerRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	// Warning or errors can be collected in a slice type
 	var diags diag.Diagnostics
 	m := meta.(*utils.Client)
 
+	setProviderDefaults(m, d)
 	filePath := d.Get("file").(string)
 	items := d.Get("items").(string)
 	fileOutputPath := d.Get("output").(string)
@@ -78,7 +255,17 @@ func resourceTransformerRead(ctx context.Context, d *schema.ResourceData, meta i
 		fileOutputPath = filePath
 		d.Set("output", filePath)
 	}
-	err := m.FileTransform(filePath, items, fileOutputPath, utils.WithOverrideArrayItems(overrideArrayItems))
+	options := append([]func(*utils.Transformer){
+		utils.WithOverrideArrayItems(overrideArrayItems),
+		utils.WithMergeStrategy(d.Get("merge_strategy").(string)),
+		utils.WithArrayItemsStrategy(d.Get("array_merge_strategy").(string)),
+		utils.WithArrayMergeKeys(arrayMergeKeys(d)),
+		utils.WithMergeRules(mergeRules(d)),
+		utils.WithPatch(d.Get("patch").(string)),
+		utils.WithKeySeparator(d.Get("key_separator").(string)),
+		utils.WithKeyPrefix(d.Get("key_prefix").(string)),
+	}, fileOptions(d)...)
+	err := m.FileTransform(filePath, items, fileOutputPath, options...)
 	if err != nil {
 		return diag.Diagnostics{
 			{
@@ -89,10 +276,37 @@ func resourceTransformerRead(ctx context.Context, d *schema.ResourceData, meta i
 		}
 	}
 
-	d.SetId(strconv.FormatInt(time.Now().Unix(), 10))
+	if err := setContentAttributes(m, d, fileOutputPath); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := setBackupPath(m, d, fileOutputPath); err != nil {
+		return diag.FromErr(err)
+	}
+	// the ID is derived from the output path, so it's stable across runs and unique per file
+	d.SetId(fileOutputPath)
 	return diags
 }
 
+// setContentAttributes reads the given file and exports its content and checksums, so that other
+// resources can be triggered when the content of the file really changes
+func setContentAttributes(m *utils.Client, d *schema.ResourceData, path string) error {
+	path, err := m.ResolvePath(path)
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	sha256Sum := sha256.Sum256(b)
+	md5Sum := md5.Sum(b)
+	d.Set("content", string(b))
+	d.Set("content_base64", base64.StdEncoding.EncodeToString(b))
+	d.Set("content_sha256", hex.EncodeToString(sha256Sum[:]))
+	d.Set("content_md5", hex.EncodeToString(md5Sum[:]))
+	return nil
+}
+
 func validateFileExt(validExt []string) func(v interface{}, s string) ([]string, []error) {
 	return func(v interface{}, s string) ([]string, []error) {
 		var validExtStr string
@@ -108,3 +322,85 @@ func validateFileExt(validExt []string) func(v interface{}, s string) ([]string,
 		return nil, []error{errors.New(fmt.Sprintf("The file extension is not supported. The supported extensions are the following: %v", validExtStr))}
 	}
 }
+
+func validatePermission(v interface{}, s string) ([]string, []error) {
+	if _, err := utils.ParsePermission(v.(string)); err != nil {
+		return nil, []error{err}
+	}
+	return nil, nil
+}
+
+// fileOptions returns the options that set the permissions and the owner of the written file
+func fileOptions(d *schema.ResourceData) []func(*utils.Transformer) {
+	options := []func(*utils.Transformer){
+		utils.WithOwner(d.Get("owner").(string), d.Get("group").(string)),
+	}
+	// permissions are validated by the schema, so parsing errors can't happen at this point
+	if v, ok := d.GetOk("file_permission"); ok {
+		mode, _ := utils.ParsePermission(v.(string))
+		options = append(options, utils.WithFilePermission(mode))
+	}
+	if v, ok := d.GetOk("directory_permission"); ok {
+		mode, _ := utils.ParsePermission(v.(string))
+		options = append(options, utils.WithDirectoryPermission(mode))
+	}
+	return append(options,
+		utils.WithIndent(d.Get("indent").(int)),
+		utils.WithBackup(d.Get("backup").(bool), d.Get("backup_retention").(int)),
+	)
+}
+
+// arrayMergeKeys returns the field identifying the elements of the arrays of each path
+func arrayMergeKeys(d *schema.ResourceData) map[string]string {
+	keys := map[string]string{}
+	for path, key := range d.Get("array_merge_keys").(map[string]interface{}) {
+		keys[path] = key.(string)
+	}
+	return keys
+}
+
+// mergeRules returns the rules of the merge_rules blocks
+func mergeRules(d *schema.ResourceData) []utils.MergeRule {
+	var rules []utils.MergeRule
+	for _, r := range d.Get("merge_rules").([]interface{}) {
+		rule := r.(map[string]interface{})
+		rules = append(rules, utils.MergeRule{
+			Path:     rule["path"].(string),
+			Strategy: rule["strategy"].(string),
+			Key:      rule["key"].(string),
+		})
+	}
+	return rules
+}
+
+// setProviderDefaults assigns the defaults configured in the provider to the attributes
+// that are not set in the configuration
+func setProviderDefaults(m *utils.Client, d *schema.ResourceData) {
+	config := d.GetRawConfig()
+	if config.IsNull() || !config.IsKnown() {
+		return
+	}
+	defaults := map[string]interface{}{
+		"override_array_items": m.OverrideArrayItems,
+		"indent":               m.Indent,
+		"backup":               m.Backup,
+		"backup_retention":     m.BackupRetention,
+	}
+	for k, v := range defaults {
+		if config.GetAttr(k).IsNull() {
+			d.Set(k, v)
+		}
+	}
+}
+
+// setBackupPath exports the most recent backup of the file, so it can be referenced (or restored)
+func setBackupPath(m *utils.Client, d *schema.ResourceData, path string) error {
+	if !d.Get("backup").(bool) {
+		return d.Set("backup_path", "")
+	}
+	backupPath, err := m.LatestBackup(path)
+	if err != nil {
+		return err
+	}
+	return d.Set("backup_path", backupPath)
+}
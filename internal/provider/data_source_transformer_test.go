@@ -1,6 +1,10 @@
 package provider
 
 import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -27,7 +31,7 @@ func TestAccReturnErrorWhenInputAndOutputFileExtensionInNotSupported(t *testing.
 				Config: `
 				data "file_transformer" "foo" {
 					file = "./test_assets/file-000.json"
-					output = "./test_assets/file-000.xml"
+					output = "./test_assets/file-000.csv"
 					items = ""
 				}
 				`,
@@ -37,7 +41,7 @@ func TestAccReturnErrorWhenInputAndOutputFileExtensionInNotSupported(t *testing.
 			{
 				Config: `
 				data "file_transformer" "foo" {
-					file = "./test_assets/file-000.xml"
+					file = "./test_assets/file-000.csv"
 					output = "./test_assets/file-000.json"
 					items = ""
 				}
@@ -67,8 +71,10 @@ func TestAccRegisterElementsWithEmptyFile(t *testing.T) {
 				Check: resource.ComposeTestCheckFunc(
 					resource.TestCheckResourceAttr("data.file_transformer.foo", "file", filePath),
 					resource.TestCheckResourceAttr("data.file_transformer.foo", "output", filePath),
+					resource.TestCheckResourceAttr("data.file_transformer.foo", "id", filePath),
 					testAccCheckFileTransformerExists("data.file_transformer.foo"),
 					testAccCheckFileTransformerCreatedFile("data.file_transformer.foo"),
+					testAccCheckFileTransformerContentChecksum("data.file_transformer.foo"),
 				),
 			},
 			{
@@ -234,6 +240,31 @@ func testAccCheckFileTransformerCreatedFile(n string) resource.TestCheckFunc {
 	}
 }
 
+func testAccCheckFileTransformerContentChecksum(n string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, _ := s.RootModule().Resources[n]
+		filePath := rs.Primary.Attributes["output"]
+		b, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		sha256Sum := sha256.Sum256(b)
+		md5Sum := md5.Sum(b)
+		expected := map[string]string{
+			"content":        string(b),
+			"content_base64": base64.StdEncoding.EncodeToString(b),
+			"content_sha256": hex.EncodeToString(sha256Sum[:]),
+			"content_md5":    hex.EncodeToString(md5Sum[:]),
+		}
+		for k, v := range expected {
+			if rs.Primary.Attributes[k] != v {
+				return fmt.Errorf("Attribute %s of %s is equal to %s whereas the expected value is %s", k, n, rs.Primary.Attributes[k], v)
+			}
+		}
+		return nil
+	}
+}
+
 func testAccFileTransformerContent(n string, expectedFileItems map[string]interface{}) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, _ := s.RootModule().Resources[n]
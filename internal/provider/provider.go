@@ -5,6 +5,7 @@ import (
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/hashicorp/terraform-provider-scaffolding/utils"
 )
 
@@ -18,8 +19,81 @@ func New(version string) func() *schema.Provider {
 			DataSourcesMap: map[string]*schema.Resource{
 				"file_transformer": dataSourceTransformer(),
 			},
-			ResourcesMap: map[string]*schema.Resource{},
-			Schema:       map[string]*schema.Schema{},
+			ResourcesMap: map[string]*schema.Resource{
+				"file_transformer": resourceTransformer(),
+			},
+			Schema: map[string]*schema.Schema{
+				"base_dir": &schema.Schema{
+					Description: "(Optional) Directory the relative paths of the transformers are resolved against. " +
+						"Defaults to the directory terraform is running in",
+					Optional: true,
+					Type:     schema.TypeString,
+				},
+				"allowed_paths": &schema.Schema{
+					Description: "(Optional) Directories (or files) the transformers are allowed to read and write, " +
+						"relative paths are resolved against `base_dir`. Every path is allowed when it's not set",
+					Optional: true,
+					Type:     schema.TypeList,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+				"symlink_policy": &schema.Schema{
+					Description: "(Optional) How files that are symbolic links are handled: `follow` reads and writes the file the link " +
+						"points to, `refuse` fails and `replace` replaces the link by a regular file. Defaults to `follow`",
+					Optional:     true,
+					Default:      utils.SymlinkFollow,
+					Type:         schema.TypeString,
+					ValidateFunc: validation.StringInSlice([]string{utils.SymlinkFollow, utils.SymlinkRefuse, utils.SymlinkReplace}, false),
+				},
+				"override_array_items": &schema.Schema{
+					Description: "(Optional) Default value of the `override_array_items` property of the transformers. Defaults to `true`",
+					Optional:    true,
+					Default:     true,
+					Type:        schema.TypeBool,
+				},
+				"indent": &schema.Schema{
+					Description: "(Optional) Default value of the `indent` property of the transformers, the number of spaces " +
+						"used to indent json and yaml files written from scratch. Defaults to `0` (json files are written in a single line)",
+					Optional:     true,
+					Default:      0,
+					Type:         schema.TypeInt,
+					ValidateFunc: validation.IntAtLeast(0),
+				},
+				"backup": &schema.Schema{
+					Description: "(Optional) Default value of the `backup` property of the transformers. Defaults to `false`",
+					Optional:    true,
+					Default:     false,
+					Type:        schema.TypeBool,
+				},
+				"backup_retention": &schema.Schema{
+					Description:  "(Optional) Default value of the `backup_retention` property of the transformers. Defaults to `5`",
+					Optional:     true,
+					Default:      5,
+					Type:         schema.TypeInt,
+					ValidateFunc: validation.IntAtLeast(0),
+				},
+				"file_permission": &schema.Schema{
+					Description: "(Optional) Permissions of the files created by the provider in octal notation, " +
+						"existing files keep their permissions. Defaults to `0644`",
+					Optional:     true,
+					Default:      "0644",
+					Type:         schema.TypeString,
+					ValidateFunc: validatePermission,
+				},
+				"directory_permission": &schema.Schema{
+					Description: "(Optional) Permissions of the directories created by the provider in octal notation. " +
+						"Defaults to `0755`",
+					Optional:     true,
+					Default:      "0755",
+					Type:         schema.TypeString,
+					ValidateFunc: validatePermission,
+				},
+				"backup_dir": &schema.Schema{
+					Description: "(Optional) Directory holding the backups taken by the transformers whose `backup` " +
+						"property is true, the directory of each file is mirrored inside of it. Defaults to the directory of each file",
+					Optional: true,
+					Type:     schema.TypeString,
+				},
+			},
 		}
 
 		p.ConfigureContextFunc = configure(version, p)
@@ -29,10 +103,30 @@ func New(version string) func() *schema.Provider {
 }
 
 func configure(version string, p *schema.Provider) func(context.Context, *schema.ResourceData) (any, diag.Diagnostics) {
-	return func(context.Context, *schema.ResourceData) (any, diag.Diagnostics) {
+	return func(ctx context.Context, d *schema.ResourceData) (any, diag.Diagnostics) {
 		// Setup a User-Agent for your API client (replace the provider name for yours):
 		// userAgent := p.UserAgent("terraform-provider-scaffolding", version)
 		c := utils.Client{}
+		filePermission, err := utils.ParsePermission(d.Get("file_permission").(string))
+		if err != nil {
+			return nil, diag.FromErr(err)
+		}
+		directoryPermission, err := utils.ParsePermission(d.Get("directory_permission").(string))
+		if err != nil {
+			return nil, diag.FromErr(err)
+		}
+		c.FilePermission = filePermission
+		c.DirectoryPermission = directoryPermission
+		c.BackupDir = d.Get("backup_dir").(string)
+		c.BaseDir = d.Get("base_dir").(string)
+		for _, path := range d.Get("allowed_paths").([]interface{}) {
+			c.AllowedPaths = append(c.AllowedPaths, path.(string))
+		}
+		c.SymlinkPolicy = d.Get("symlink_policy").(string)
+		c.OverrideArrayItems = d.Get("override_array_items").(bool)
+		c.Indent = d.Get("indent").(int)
+		c.Backup = d.Get("backup").(bool)
+		c.BackupRetention = d.Get("backup_retention").(int)
 		// TODO: myClient.UserAgent = userAgent
 
 		return &c, nil
@@ -0,0 +1,242 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-provider-scaffolding/utils"
+)
+
+func resourceTransformer() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceTransformerCreate,
+		ReadContext:   resourceTransformerRead,
+		UpdateContext: resourceTransformerUpdate,
+		DeleteContext: resourceTransformerDelete,
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceTransformerImport,
+		},
+		CustomizeDiff: customdiff.All(
+			customdiff.ComputedIf("changes", itemsChanged),
+			customdiff.ComputedIf("backup_path", itemsChanged),
+			customdiff.ComputedIf("content", itemsChanged),
+			customdiff.ComputedIf("content_base64", itemsChanged),
+			customdiff.ComputedIf("content_sha256", itemsChanged),
+			customdiff.ComputedIf("content_md5", itemsChanged),
+		),
+		Description: "The `file_transformer` resource merges the content provided in `items` into the given file. " +
+			"Unlike the `file_transformer` data source, the file is only written during `terraform apply` " +
+			"(on create and update), so `terraform plan` and `terraform refresh` have no side effects on the file system. " +
+			"When the resource is destroyed, the keys added by the transformer are removed from the file and the keys it " +
+			"overwrote get their previous value back. Changes made outside of terraform to the keys defined in `items` " +
+			"are detected on refresh and reported in the plan. " +
+			"Currently supported file extensions are json, .env, yaml (or yml), toml, xml, ini (.ini, .cfg, .gitconfig and systemd unit files), properties and hcl (.tfvars and .hcl)" +
+			"\n" +
+			"**Warning** It is necessary that you grant sufficient permissions so that the provider can read " +
+			"and make changes to the contents of the specified file (_chmod +rw_).",
+		Schema: resourceTransformerSchema(),
+	}
+}
+
+// resourceTransformerSchema returns the attributes of the data source, changing the paths of the file forces a
+// new resource and the keys changed by the transformer are kept in the state to be restored on destroy
+func resourceTransformerSchema() map[string]*schema.Schema {
+	s := transformerSchema()
+	s["file"].ForceNew = true
+	s["file"].Description += " Changing this property forces a new resource to be created."
+	s["output"].Computed = true
+	s["output"].ForceNew = true
+	s["output"].Description += " Changing this property forces a new resource to be created."
+	s["items"].DiffSuppressFunc = func(k, old, new string, d *schema.ResourceData) bool {
+		return utils.ItemsEqual(old, new)
+	}
+	s["patch"].Description = "(Optional) JSON Patch ([RFC 6902](https://www.rfc-editor.org/rfc/rfc6902)) applied to the file once `items` are merged, " +
+		"that is a JSON encoded list of `add`, `remove`, `replace`, `move`, `copy` and `test` operations. When a `test` " +
+		"operation does not match, the file is left untouched and the apply fails. Only applicable to json, yaml, toml, ini, xml and hcl files."
+	s["changes"] = &schema.Schema{
+		Description: "JSON encoded list of the keys added or overwritten by the transformer, together with " +
+			"their previous values. It's used to restore the file when the resource is destroyed.",
+		Computed: true,
+		Type:     schema.TypeString,
+	}
+	return s
+}
+
+func itemsChanged(ctx context.Context, d *schema.ResourceDiff, meta interface{}) bool {
+	return d.HasChanges("items", "patch", "override_array_items", "array_merge_strategy", "array_merge_keys", "merge_rules", "merge_strategy", "key_separator", "key_prefix")
+}
+
+func resourceTransformerCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	m := meta.(*utils.Client)
+
+	setProviderDefaults(m, d)
+	filePath := d.Get("file").(string)
+	fileOutputPath := d.Get("output").(string)
+	//If the outputPath value is not provided, the input filePath value is assigned to the outputPath value
+	if fileOutputPath == "" {
+		fileOutputPath = filePath
+		d.Set("output", filePath)
+	}
+	if err := transform(m, d); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(fileOutputPath)
+	return resourceTransformerRead(ctx, d, meta)
+}
+
+func resourceTransformerRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	m := meta.(*utils.Client)
+
+	items := d.Get("items").(string)
+	currentItems, err := m.CurrentItems(
+		d.Get("output").(string),
+		items,
+		utils.WithOverrideArrayItems(d.Get("override_array_items").(bool)),
+		utils.WithMergeStrategy(d.Get("merge_strategy").(string)),
+		utils.WithArrayItemsStrategy(d.Get("array_merge_strategy").(string)),
+		utils.WithArrayMergeKeys(arrayMergeKeys(d)),
+		utils.WithMergeRules(mergeRules(d)),
+		utils.WithKeySeparator(d.Get("key_separator").(string)),
+		utils.WithKeyPrefix(d.Get("key_prefix").(string)),
+	)
+	// the file is the remote object managed by this resource, when it no longer exists the
+	// resource is removed from the state so that the next plan recreates it
+	if os.IsNotExist(err) {
+		d.SetId("")
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	// when the managed keys were changed outside of terraform, the values found in the file are
+	// stored in the state, so the next plan shows the difference against the desired items
+	if !utils.ItemsEqual(currentItems, items) {
+		d.Set("items", currentItems)
+	}
+	if err := setContentAttributes(m, d, d.Get("output").(string)); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := setBackupPath(m, d, d.Get("output").(string)); err != nil {
+		return diag.FromErr(err)
+	}
+	return diags
+}
+
+func resourceTransformerUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	m := meta.(*utils.Client)
+
+	setProviderDefaults(m, d)
+	if err := transform(m, d); err != nil {
+		return diag.FromErr(err)
+	}
+	return resourceTransformerRead(ctx, d, meta)
+}
+
+func resourceTransformerDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	m := meta.(*utils.Client)
+
+	changes, err := decodeChanges(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	// keys added by the transformer are removed and overwritten keys get their previous value back
+	if err := m.Revert(d.Get("output").(string), changes, fileOptions(d)...); err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId("")
+	return diags
+}
+
+// resourceTransformerImport seeds the state from an existing file. The import ID is the path of the file,
+// optionally followed by a colon and the dotted path of the key to be managed (e.g. `./docker-compose.yml:services.web`)
+func resourceTransformerImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	m := meta.(*utils.Client)
+
+	filePath, keyPath := parseImportID(d.Id())
+	if _, errs := validateFileExt(supportedFileExt)(filePath, "file"); len(errs) > 0 {
+		return nil, errs[0]
+	}
+	items, err := m.ImportItems(filePath, keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	d.Set("file", filePath)
+	d.Set("output", filePath)
+	d.Set("override_array_items", m.OverrideArrayItems)
+	d.Set("merge_strategy", utils.DeepMergeStrategy)
+	d.Set("key_separator", "__")
+	d.Set("key_prefix", "")
+	d.Set("indent", m.Indent)
+	d.Set("backup", m.Backup)
+	d.Set("backup_retention", m.BackupRetention)
+	d.Set("items", items)
+	d.SetId(filePath)
+	return []*schema.ResourceData{d}, nil
+}
+
+// parseImportID splits the import ID in file path and key path, the colon is only taken as separator when
+// it comes after the file extension, so paths containing colons (e.g. windows drive letters) are supported
+func parseImportID(id string) (string, []string) {
+	i := strings.LastIndex(id, ":")
+	if i < 0 || filepath.Ext(id[:i]) == "" || strings.ContainsAny(id[i+1:], `/\`) {
+		return id, nil
+	}
+	if id[i+1:] == "" {
+		return id[:i], nil
+	}
+	return id[:i], strings.Split(id[i+1:], ".")
+}
+
+// transform merges items into the file and records the keys it touched, keys recorded by
+// previous applies keep their original value so that the file can be fully restored on destroy
+func transform(m *utils.Client, d *schema.ResourceData) error {
+	previousChanges, err := decodeChanges(d)
+	if err != nil {
+		return err
+	}
+	options := append([]func(*utils.Transformer){
+		utils.WithOverrideArrayItems(d.Get("override_array_items").(bool)),
+		utils.WithMergeStrategy(d.Get("merge_strategy").(string)),
+		utils.WithArrayItemsStrategy(d.Get("array_merge_strategy").(string)),
+		utils.WithArrayMergeKeys(arrayMergeKeys(d)),
+		utils.WithMergeRules(mergeRules(d)),
+		utils.WithPatch(d.Get("patch").(string)),
+		utils.WithKeySeparator(d.Get("key_separator").(string)),
+		utils.WithKeyPrefix(d.Get("key_prefix").(string)),
+		utils.WithPreviousChanges(previousChanges),
+	}, fileOptions(d)...)
+	changes, err := m.FileTransformChanges(
+		d.Get("file").(string),
+		d.Get("items").(string),
+		d.Get("output").(string),
+		options...,
+	)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	return d.Set("changes", string(b))
+}
+
+func decodeChanges(d *schema.ResourceData) ([]utils.Change, error) {
+	var changes []utils.Change
+	if v := d.Get("changes").(string); v != "" {
+		if err := json.Unmarshal([]byte(v), &changes); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,248 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestAccResourceTransformerLifecycle(t *testing.T) {
+	filePath := "./test_assets/resource-001.json"
+	initFileContent := map[string]interface{}{"name": "Lyon", "players": []string{"Tolisso", "Lacazette"}}
+	b, _ := json.Marshal(initFileContent)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		CheckDestroy: func(*terraform.State) error {
+			os.Remove(filePath)
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				PreConfig: func() {
+					//Create file & register Content
+					file, _ := os.OpenFile(filePath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0666)
+					file.Write(b)
+					file.Close()
+				},
+				Config: testAccResourceTransformerConfig(filePath, "Garcia"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("file_transformer.foo", "id", filePath),
+					resource.TestCheckResourceAttr("file_transformer.foo", "output", filePath),
+					testAccCheckFileTransformerContentChecksum("file_transformer.foo"),
+					testAccFileTransformerContent("file_transformer.foo", map[string]interface{}{
+						"name":    "Lyon",
+						"players": []interface{}{"Tolisso", "Lacazette"},
+						"coach":   "Garcia",
+					}),
+				),
+			},
+			{
+				Config: testAccResourceTransformerConfig(filePath, "Genesio"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("file_transformer.foo", "id", filePath),
+					testAccFileTransformerContent("file_transformer.foo", map[string]interface{}{
+						"name":    "Lyon",
+						"players": []interface{}{"Tolisso", "Lacazette"},
+						"coach":   "Genesio",
+					}),
+				),
+			},
+			// managed keys changed outside of terraform are detected & restored
+			{
+				PreConfig: func() {
+					b, _ := json.Marshal(map[string]interface{}{"name": "Lyon", "players": []string{"Tolisso"}, "coach": "Bosz"})
+					os.WriteFile(filePath, b, 0666)
+				},
+				Config: testAccResourceTransformerConfig(filePath, "Genesio"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccFileTransformerContent("file_transformer.foo", map[string]interface{}{
+						"name":    "Lyon",
+						"players": []interface{}{"Tolisso"},
+						"coach":   "Genesio",
+					}),
+				),
+			},
+			// the file is recreated when it's removed outside of terraform
+			{
+				PreConfig: func() {
+					os.Remove(filePath)
+				},
+				Config: testAccResourceTransformerConfig(filePath, "Genesio"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccFileTransformerContent("file_transformer.foo", map[string]interface{}{
+						"coach": "Genesio",
+					}),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceTransformerRevertOnDestroy(t *testing.T) {
+	filePath := "./test_assets/resource-002.json"
+	initFileContent := map[string]interface{}{"name": "Lyon", "coach": "Garcia"}
+	b, _ := json.Marshal(initFileContent)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		CheckDestroy: func(*terraform.State) error {
+			defer os.Remove(filePath)
+			//keys added by the transformer are removed & overwritten keys are restored
+			fileB, _ := os.ReadFile(filePath)
+			var fileItems map[string]interface{}
+			json.Unmarshal(fileB, &fileItems)
+			if !reflect.DeepEqual(fileItems, initFileContent) {
+				return fmt.Errorf("Content of file %s is equal to %v whereas the expected content is %v", filePath, fileItems, initFileContent)
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				PreConfig: func() {
+					//Create file & register Content
+					file, _ := os.OpenFile(filePath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0666)
+					file.Write(b)
+					file.Close()
+				},
+				Config: `
+				resource "file_transformer" "foo" {
+					file = "./test_assets/resource-002.json"
+					items = jsonencode({
+						"coach"   = "Genesio"
+						"stadium" = "Groupama"
+					})
+				}
+				`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrSet("file_transformer.foo", "changes"),
+					testAccFileTransformerContent("file_transformer.foo", map[string]interface{}{
+						"name":    "Lyon",
+						"coach":   "Genesio",
+						"stadium": "Groupama",
+					}),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceTransformerTomlLifecycle(t *testing.T) {
+	filePath := "./test_assets/resource-004.toml"
+	initFileContent := "created = 1979-05-27T07:32:00Z\nname = 'api'\nport = 80\n"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		CheckDestroy: func(s *terraform.State) error {
+			defer os.Remove(filePath)
+			//integers & datetimes restored from the changes keep their toml types
+			return testAccCheckFileContent(filePath, initFileContent)(s)
+		},
+		Steps: []resource.TestStep{
+			{
+				PreConfig: func() {
+					os.WriteFile(filePath, []byte(initFileContent), 0666)
+				},
+				Config: testAccResourceTransformerTomlConfig(filePath, `{"port" = 8080, "created" = "2023-01-02T15:04:05Z"}`),
+				Check:  testAccCheckFileContent(filePath, "created = 2023-01-02T15:04:05Z\nname = 'api'\nport = 8080\n"),
+			},
+			{
+				Config: testAccResourceTransformerTomlConfig(filePath, `{"workers" = 4}`),
+				Check:  testAccCheckFileContent(filePath, "created = 1979-05-27T07:32:00Z\nname = 'api'\nport = 80\nworkers = 4\n"),
+			},
+		},
+	})
+}
+
+func TestAccResourceTransformerImport(t *testing.T) {
+	filePath := "./test_assets/resource-003.json"
+	initFileContent := map[string]interface{}{"name": "Lyon", "coach": "Garcia"}
+	b, _ := json.Marshal(initFileContent)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		CheckDestroy: func(*terraform.State) error {
+			os.Remove(filePath)
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				PreConfig: func() {
+					os.WriteFile(filePath, b, 0666)
+				},
+				Config: testAccResourceTransformerConfig(filePath, "Garcia"),
+			},
+			{
+				ResourceName:            "file_transformer.foo",
+				ImportState:             true,
+				ImportStateId:           filePath + ":coach",
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"changes"},
+			},
+		},
+	})
+}
+
+func TestParseImportID(t *testing.T) {
+	testContent := []struct {
+		id       string
+		path     string
+		keysPath []string
+	}{
+		{id: "./docker-compose.yml", path: "./docker-compose.yml"},
+		{id: "./docker-compose.yml:services.web", path: "./docker-compose.yml", keysPath: []string{"services", "web"}},
+		{id: "C:\\config\\app.json", path: "C:\\config\\app.json"},
+		{id: "C:\\config\\app.json:name", path: "C:\\config\\app.json", keysPath: []string{"name"}},
+		{id: "./.env:DB_PASSWORD", path: "./.env", keysPath: []string{"DB_PASSWORD"}},
+	}
+	for _, value := range testContent {
+		path, keysPath := parseImportID(value.id)
+		if path != value.path || !reflect.DeepEqual(keysPath, value.keysPath) {
+			t.Errorf("parseImportID(%q) = %q, %v, expected %q, %v", value.id, path, keysPath, value.path, value.keysPath)
+		}
+	}
+}
+
+func testAccResourceTransformerTomlConfig(filePath, items string) string {
+	return fmt.Sprintf(`
+		resource "file_transformer" "foo" {
+			file  = "%s"
+			items = jsonencode(%s)
+		}
+	`, filePath, items)
+}
+
+func testAccCheckFileContent(filePath, expected string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		b, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		if string(b) != expected {
+			return fmt.Errorf("Content of file %s is equal to %q whereas the expected content is %q", filePath, b, expected)
+		}
+		return nil
+	}
+}
+
+func testAccResourceTransformerConfig(filePath, coach string) string {
+	return fmt.Sprintf(`
+		resource "file_transformer" "foo" {
+			file = "%s"
+			items = jsonencode(
+				{
+					"coach" = "%s"
+				}
+			)
+		}
+	`, filePath, coach)
+}
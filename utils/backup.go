@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backups are named after the file followed by the time they were taken (e.g. `config.json.20230102T150405.000000000.bak`),
+// so sorting them by name sorts them by age
+const backupTimeLayout = "20060102T150405.000000000"
+
+// WithBackup enables (or disables) saving the previous content of the file before it's overwritten, only
+// the given number of backups (the most recent ones) are kept, all of them are kept when retention is 0
+func WithBackup(enabled bool, retention int) func(*Transformer) {
+	return func(m *Transformer) {
+		m.backup = enabled
+		m.backupRetention = retention
+	}
+}
+
+// backupFile saves the current content of the file, when it exists and it's about to change, and removes
+// the backups exceeding the retention
+func (cl Client) backupFile(path string, b []byte, t Transformer) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	current, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(current, b) {
+		return nil
+	}
+
+	dir, err := cl.backupDir(path)
+	if err != nil {
+		return err
+	}
+	backupPath := filepath.Join(dir, filepath.Base(path)+"."+time.Now().UTC().Format(backupTimeLayout)+".bak")
+	// backups hold the same content as the file, so they get the same permissions
+	err = cl.writeFile(backupPath, current, Transformer{filePermission: info.Mode().Perm(), directoryPermission: t.directoryPermission})
+	if err != nil {
+		return err
+	}
+
+	backups, err := cl.Backups(path)
+	if err != nil || t.backupRetention <= 0 {
+		return err
+	}
+	for len(backups) > t.backupRetention {
+		if err := os.Remove(backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// Backups returns the backups of the file, sorted from the oldest to the most recent one
+func (cl Client) Backups(path string) ([]string, error) {
+	path, err := cl.ResolvePath(path)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := cl.backupDir(path)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	prefix := filepath.Base(path) + "."
+	var backups []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".bak") {
+			continue
+		}
+		if _, err := time.Parse(backupTimeLayout, strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".bak")); err != nil {
+			continue
+		}
+		backups = append(backups, filepath.Join(dir, name))
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// LatestBackup returns the most recent backup of the file, an empty string is returned when there are none
+func (cl Client) LatestBackup(path string) (string, error) {
+	backups, err := cl.Backups(path)
+	if err != nil || len(backups) == 0 {
+		return "", err
+	}
+	return backups[len(backups)-1], nil
+}
+
+// backupDir returns the directory holding the backups of the file, backups are placed next to the file
+// unless a backup directory is configured. In that case the canonical directory of the file is mirrored
+// inside of it (e.g. `/etc/app/config.json` is saved in `<backup_dir>/etc/app`), so files sharing the same
+// name don't share their backups
+func (cl Client) backupDir(path string) (string, error) {
+	if cl.BackupDir == "" {
+		return filepath.Dir(path), nil
+	}
+	dir, err := canonicalPath(filepath.Dir(path))
+	if err != nil {
+		return "", err
+	}
+	dir = strings.TrimPrefix(dir, filepath.VolumeName(dir))
+	return filepath.Join(cl.joinBaseDir(cl.BackupDir), dir), nil
+}
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Change describes a key written by a transformation. Existed is false when the key was added
+// by the transformation, otherwise Previous holds the value the key had before being overwritten
+type Change struct {
+	Path     []string    `json:"path"`
+	Existed  bool        `json:"existed"`
+	Previous interface{} `json:"previous,omitempty"`
+}
+
+func WithPreviousChanges(changes []Change) func(*Transformer) {
+	return func(m *Transformer) {
+		m.previousChanges = changes
+	}
+}
+
+// diffChanges compares the content of a file before and after a transformation and returns the keys
+// that were added, overwritten or removed. Nested maps are compared key by key, so only the keys that
+// were actually touched are recorded
+func diffChanges(before, after map[string]interface{}, path []string) []Change {
+	var changes []Change
+	for _, k := range unionKeys(before, after) {
+		beforeValue, inBefore := before[k]
+		afterValue, inAfter := after[k]
+		keyPath := append(append([]string{}, path...), k)
+
+		beforeMap, beforeIsMap := beforeValue.(map[string]interface{})
+		afterMap, afterIsMap := afterValue.(map[string]interface{})
+		switch {
+		case inBefore && inAfter && beforeIsMap && afterIsMap:
+			changes = append(changes, diffChanges(beforeMap, afterMap, keyPath)...)
+		case inBefore && inAfter && reflect.DeepEqual(beforeValue, afterValue):
+			continue
+		case !inBefore:
+			changes = append(changes, Change{Path: keyPath, Existed: false})
+		default:
+			changes = append(changes, Change{Path: keyPath, Existed: true, Previous: beforeValue})
+		}
+	}
+	return changes
+}
+
+// revertChanges restores the content of a file to the state it had before the changes were applied.
+// Changes are undone from the most recent to the oldest, keys added by the transformation are removed
+// and overwritten keys get their previous value back, every other key is left untouched
+func revertChanges(content map[string]interface{}, changes []Change) {
+	for i := len(changes) - 1; i >= 0; i-- {
+		c := changes[i]
+		if len(c.Path) == 0 {
+			continue
+		}
+		if !c.Existed {
+			parent, ok := lookupMap(content, c.Path[:len(c.Path)-1], false)
+			if ok {
+				delete(parent, c.Path[len(c.Path)-1])
+			}
+			continue
+		}
+		parent, _ := lookupMap(content, c.Path[:len(c.Path)-1], true)
+		parent[c.Path[len(c.Path)-1]] = deepCopy(c.Previous)
+	}
+}
+
+// lookupMap returns the map found at the given path, when create is true the missing
+// (or non-map) levels are replaced by empty maps
+func lookupMap(content map[string]interface{}, path []string, create bool) (map[string]interface{}, bool) {
+	current := content
+	for _, k := range path {
+		m, ok := current[k].(map[string]interface{})
+		if !ok {
+			if !create {
+				return nil, false
+			}
+			m = map[string]interface{}{}
+			current[k] = m
+		}
+		current = m
+	}
+	return current, true
+}
+
+func isPathPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// deepCopy copies the maps and slices produced by the decoders, so a value can be changed
+// without affecting the original one
+func deepCopy(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(value))
+		for k, e := range value {
+			c[k] = deepCopy(e)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(value))
+		for i, e := range value {
+			c[i] = deepCopy(e)
+		}
+		return c
+	}
+	return v
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffChanges(t *testing.T) {
+	t.Run("Record added, overwritten and removed keys", func(t *testing.T) {
+		before := map[string]interface{}{
+			"coach": "Ancelotti",
+			"club": map[string]interface{}{
+				"stadium": "Bernabeu",
+			},
+			"titles":  []interface{}{"UCL"},
+			"captain": "Nacho",
+		}
+		after := map[string]interface{}{
+			"coach": "Mourinho",
+			"club": map[string]interface{}{
+				"stadium": "Bernabeu",
+				"name":    "Real Madrid",
+			},
+			"titles": []interface{}{"UCL"},
+		}
+		expected := []Change{
+			{Path: []string{"captain"}, Existed: true, Previous: "Nacho"},
+			{Path: []string{"club", "name"}, Existed: false},
+			{Path: []string{"coach"}, Existed: true, Previous: "Ancelotti"},
+		}
+		assert.Equal(t, expected, diffChanges(before, after, nil))
+	})
+}
+
+func TestRevertChanges(t *testing.T) {
+	t.Run("Remove added keys & restore overwritten keys leaving other keys untouched", func(t *testing.T) {
+		content := map[string]interface{}{
+			"coach": "Mourinho",
+			"club": map[string]interface{}{
+				"stadium": "Bernabeu",
+				"name":    "Real Madrid",
+			},
+			"president": "Florentino",
+		}
+		changes := []Change{
+			{Path: []string{"club", "name"}, Existed: false},
+			{Path: []string{"coach"}, Existed: true, Previous: "Ancelotti"},
+			{Path: []string{"titles"}, Existed: true, Previous: []interface{}{"UCL"}},
+		}
+		revertChanges(content, changes)
+		assert.Equal(t, map[string]interface{}{
+			"coach": "Ancelotti",
+			"club": map[string]interface{}{
+				"stadium": "Bernabeu",
+			},
+			"president": "Florentino",
+			"titles":    []interface{}{"UCL"},
+		}, content)
+	})
+}
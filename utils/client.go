@@ -1,25 +1,67 @@
 package utils
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
 	"gopkg.in/yaml.v3"
 )
 
 type Client struct {
+	// BaseDir is the directory relative paths are resolved against, defaults to the working directory
+	BaseDir string
+	// AllowedPaths restricts the files read and written by the client to the ones placed in these
+	// paths (directories or files), every path is allowed when it's empty
+	AllowedPaths []string
+	// OverrideArrayItems is the default behaviour of arrays, see WithOverrideArrayItems
+	OverrideArrayItems bool
+	// Indent is the number of spaces used to indent new json and yaml files, defaults to the
+	// format default (json files are written in a single line)
+	Indent int
+	// Backup enables backups by default, keeping BackupRetention backups of each file (see WithBackup)
+	Backup          bool
+	BackupRetention int
+	// FilePermission is the permission of the files created by the client, defaults to 0644
+	FilePermission os.FileMode
+	// DirectoryPermission is the permission of the directories created by the client, defaults to 0755
+	DirectoryPermission os.FileMode
+	// BackupDir is the directory holding the backups of the files, defaults to the directory of each file
+	BackupDir string
+	// SymlinkPolicy decides how files that are symbolic links are handled (SymlinkFollow, SymlinkRefuse or
+	// SymlinkReplace), defaults to SymlinkFollow
+	SymlinkPolicy string
 }
 
 type Transformer struct {
-	path               string
-	outputPath         string
-	items              string
-	overrideArrayItems bool
+	path                string
+	outputPath          string
+	items               string
+	overrideArrayItems  bool
+	previousChanges     []Change
+	keySeparator        string
+	keyPrefix           string
+	filePermission      os.FileMode
+	directoryPermission os.FileMode
+	owner               string
+	group               string
+	backup              bool
+	backupRetention     int
+	indent              int
+	mergeStrategy       string
+	patch               string
+	arrayMergeKeys      map[string]string
+	arrayStrategy       string
+	mergeRules          []MergeRule
 }
 
 func WithOverrideArrayItems(append bool) func(*Transformer) {
@@ -28,132 +70,519 @@ func WithOverrideArrayItems(append bool) func(*Transformer) {
 	}
 }
 
+// WithMergeStrategy sets how items are merged into the file (DeepMergeStrategy or MergePatchStrategy),
+// defaults to DeepMergeStrategy
+func WithMergeStrategy(strategy string) func(*Transformer) {
+	return func(m *Transformer) {
+		m.mergeStrategy = strategy
+	}
+}
+
+// WithArrayItemsStrategy sets how arrays are merged (ArrayReplace, ArrayAppend, ArrayUnion or ArrayIndex), it takes
+// precedence over WithOverrideArrayItems
+func WithArrayItemsStrategy(strategy string) func(*Transformer) {
+	return func(m *Transformer) {
+		m.arrayStrategy = strategy
+	}
+}
+
+// WithMergeRules sets the rules assigning merge strategies to the paths matched by their selector, the first
+// rule matching a path is used and rules take precedence over every other array setting
+func WithMergeRules(rules []MergeRule) func(*Transformer) {
+	return func(m *Transformer) {
+		m.mergeRules = rules
+	}
+}
+
+// WithArrayMergeKeys sets the field identifying the elements of the arrays of objects placed in the given
+// paths (keys joined with dots, e.g. `spec.containers`), elements of items are deep merged into the element
+// of the file with the same value of the field and new elements are appended
+func WithArrayMergeKeys(keys map[string]string) func(*Transformer) {
+	return func(m *Transformer) {
+		m.arrayMergeKeys = keys
+	}
+}
+
+// WithIndent sets the number of spaces used to indent the file, when it's written from scratch
+func WithIndent(indent int) func(*Transformer) {
+	return func(m *Transformer) {
+		m.indent = indent
+	}
+}
+
+// newTransformer returns the transformer of the given files using the settings of the client as defaults,
+// paths are resolved against the base directory of the client
+func (cl Client) newTransformer(path, items, outputPath string, options []func(*Transformer)) (Transformer, error) {
+	t := Transformer{
+		path:               path,
+		items:              items,
+		outputPath:         outputPath,
+		overrideArrayItems: cl.OverrideArrayItems,
+		keySeparator:       defaultKeySeparator,
+		backup:             cl.Backup,
+		backupRetention:    cl.BackupRetention,
+		indent:             cl.Indent,
+		mergeStrategy:      DeepMergeStrategy,
+	}
+	for _, opt := range options {
+		opt(&t)
+	}
+	var err error
+	if t.path, err = cl.ResolvePath(t.path); err != nil {
+		return t, err
+	}
+	if t.outputPath != "" {
+		if t.outputPath, err = cl.ResolvePath(t.outputPath); err != nil {
+			return t, err
+		}
+	}
+	return t, nil
+}
+
 type Unmarshal func(in []byte, out interface{}) (err error)
 type Marshal func(in interface{}) (out []byte, err error)
 
-type DataDecoder struct {
-	unmarshal Unmarshal
-	marshal   Marshal
-}
+// Patch encodes the content reusing the original content of the file, so that the parts
+// of the document that were not changed keep their layout
+type Patch func(original []byte, in interface{}) (out []byte, err error)
 
 var (
 	supportedFileExtDecode = map[string]Unmarshal{
 		".yaml": yaml.Unmarshal,
 		".yml":  yaml.Unmarshal,
 		".json": json.Unmarshal,
+		".toml": toml.Unmarshal,
 	}
 	supportedFileExtEncode = map[string]Marshal{
 		".yaml": yaml.Marshal,
 		".yml":  yaml.Marshal,
 		".json": json.Marshal,
+		".toml": toml.Marshal,
 	}
+	supportedFileExtPatch = map[string]Patch{}
 )
 
 func (cl Client) FileTransform(path, content, outputPath string, options ...func(*Transformer)) error {
-	t := Transformer{path: path, items: content, outputPath: outputPath, overrideArrayItems: false}
-	for _, opt := range options {
-		opt(&t)
+	_, err := cl.FileTransformChanges(path, content, outputPath, options...)
+	return err
+}
+
+// FileTransformChanges merges content into the file and returns the keys added, overwritten or
+// removed by the transformation, so that they can be reverted later on
+func (cl Client) FileTransformChanges(path, content, outputPath string, options ...func(*Transformer)) ([]Change, error) {
+	t, err := cl.newTransformer(path, content, outputPath, options)
+	if err != nil {
+		return nil, err
+	}
+	b, err := cl.readFile(t.path, t)
+	if err != nil {
+		return nil, err
+	}
+	if isProperties(t.path) != isProperties(t.outputPath) {
+		return nil, fmt.Errorf("Properties files can only be written to properties files, can't write %s to %s", path, outputPath)
+	}
+	if t.patch != "" && (isDotEnv(t.outputPath) || isProperties(t.path)) {
+		return nil, fmt.Errorf("Patch can't be applied to %s, patches are only supported by json, yaml, toml, ini, xml and hcl files", outputPath)
+	}
+	if isDotEnv(t.outputPath) {
+		return cl.dotEnv(b, t)
+	}
+	if isProperties(t.path) {
+		return cl.properties(b, t)
 	}
-	file, err := cl.ReadHandler(t.path)
+	return cl.jsonAndYaml(b, t)
+}
+
+// Revert undoes the changes recorded by FileTransformChanges in the given file,
+// keys that were not written by the transformation are left untouched
+func (cl Client) Revert(path string, changes []Change, options ...func(*Transformer)) error {
+	t, err := cl.newTransformer(path, "", path, options)
 	if err != nil {
 		return err
 	}
+	path = t.path
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if isDotEnv(path) {
+		fileContent, err := godotenv.Unmarshal(string(b))
+		if err != nil {
+			return err
+		}
+		content := stringMapToMap(fileContent)
+		revertChanges(content, changes)
+		envB, err := envPatch(b, mapToStringMap(content))
+		if err != nil {
+			return err
+		}
+		return cl.writeFile(path, envB, t)
+	}
+	if isProperties(path) {
+		content := stringMapToMap(decodeProperties(b))
+		revertChanges(content, changes)
+		return cl.writeFile(path, propertiesPatch(b, mapToStringMap(content)), t)
+	}
+
+	content, err := decodeFile(b, path)
+	if err != nil {
+		return err
+	}
+	fileContent := deepCopy(content)
+	revertChanges(content, changes)
+	if isToml(path) {
+		// the previous values are decoded from json, so their toml types are restored
+		content = tomlTypes(content, fileContent).(map[string]interface{})
+	}
+	contentB, err := encodeFile(b, path, content, t.indent)
+	if err != nil {
+		return err
+	}
+	return cl.writeFile(path, contentB, t)
+}
+
+func (cl Client) readFile(path string, t Transformer) ([]byte, error) {
+	file, err := cl.openFile(path, t)
+	if err != nil {
+		return nil, err
+	}
 	defer file.Close()
 
 	fileInfo, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
 	// create slice that hold file content in bytes, with size equal to file size
 	b := make([]byte, fileInfo.Size())
 	_, err = file.Read(b)
+	if err != nil && fileInfo.Size() > 0 {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (cl Client) jsonAndYaml(b []byte, t Transformer) ([]Change, error) {
+	var dstContent map[string]interface{}
+	if isDotEnv(t.path) {
+		// variables are split by the key separator, so they can be written as nested content
+		fileContent, err := godotenv.Unmarshal(string(b))
+		if err != nil {
+			return nil, err
+		}
+		dstContent = unflattenEnv(fileContent, t)
+	} else {
+		var err error
+		dstContent, err = decodeFile(b, t.path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	srcContent, err := nestedItems(t)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if ok, _ := regexp.MatchString(".env", filepath.Ext(path)); ok {
-		return cl.dotEnv(b, path, content)
+	// keys written by the previous transformations are restored before merging the new content, so that
+	// every transformation starts from the original values (appended arrays would grow otherwise)
+	fileContent := deepCopy(dstContent)
+	revertChanges(dstContent, t.previousChanges)
+	if isToml(t.path) {
+		dstContent = tomlTypes(dstContent, fileContent).(map[string]interface{})
 	}
-	return cl.jsonAndYaml(b, t)
-}
-func (cl Client) jsonAndYaml(b []byte, t Transformer) error {
+	if isIni(t.path) {
+		alignIniArrays(srcContent, dstContent)
+	}
+	if isXml(t.path) {
+		alignXmlArrays(srcContent, dstContent)
+	}
+	originalContent := deepCopy(dstContent).(map[string]interface{})
 
-	dstContent := map[string]interface{}{}
-	srcContent := map[string]interface{}{}
-	dataDecoder := DataDecoder{
-		unmarshal: supportedFileExtDecode[filepath.Ext(t.path)],
-		marshal:   supportedFileExtEncode[filepath.Ext(t.outputPath)],
+	mergedContent, err := Merge(srcContent, dstContent, WithOverrideArray(t.overrideArrayItems), WithArrayStrategy(t.arrayStrategy),
+		WithStrategy(t.mergeStrategy), WithMergeKeys(t.arrayMergeKeys), WithRules(t.mergeRules))
+	if err != nil {
+		return nil, err
+	}
+	if t.patch != "" {
+		if mergedContent, err = ApplyPatch(mergedContent.(map[string]interface{}), t.patch); err != nil {
+			return nil, err
+		}
 	}
 
-	// Unmarshal empty json/map (empty byte array=>b=0) we will get 'unexpected end of JSON input' error
-	//The conditional below aims to workaround this error
-	if len(b) > 0 {
-		err := dataDecoder.unmarshal(b, &dstContent)
-		if err != nil {
-			return errors.New(fmt.Sprintf("Content of file %s is malformed: %s", t.path, err.Error()))
+	if isToml(t.outputPath) {
+		// only the floats of toml files are known to be floats, the numbers of items may be integers
+		var tomlContent interface{}
+		if isToml(t.path) {
+			tomlContent = originalContent
 		}
+		mergedContent = tomlTypes(mergedContent, tomlContent)
 	}
-	err := json.Unmarshal([]byte(t.items), &srcContent)
-	if err != nil {
-		return err
+
+	// the layout of the source file is only reused when the output file has the same format
+	original := b
+	if filepath.Ext(t.path) != filepath.Ext(t.outputPath) {
+		original = nil
 	}
-	mergedContent, err := Merge(srcContent, dstContent, WithOverrideArray(t.overrideArrayItems))
+	mergedContentB, err := encodeFile(original, t.outputPath, mergedContent, t.indent)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	var mergedContentB []byte
+	// replace all content of file with merged content
+	err = cl.writeFile(t.outputPath, mergedContentB, t)
+	if err != nil {
+		return nil, err
+	}
+	return diffChanges(originalContent, mergedContent.(map[string]interface{}), nil), nil
+}
 
-	mergedContentB, err = dataDecoder.marshal(mergedContent)
+// dotEnv writes the variables to a .env file, files with other formats are flattened
+// to variables using the key separator and prefix
+func (cl Client) dotEnv(b []byte, t Transformer) ([]Change, error) {
+	fileContent, err := envContent(b, t.path, t)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	envMap, err := envItems(t)
+	if err != nil {
+		return nil, err
 	}
+	removedKeys, err := t.removedKeys(t.keySeparator, t.keyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	content := stringMapToMap(fileContent)
+	revertChanges(content, t.previousChanges)
+	originalContent := deepCopy(content).(map[string]interface{})
 
-	fileWriteP, err := os.OpenFile(t.outputPath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0777)
-	fileWriteP.Truncate(0)
+	// merging environment variables to map that contains provided file (.env) environment variables
+	for k, v := range envMap {
+		content[k] = v
+	}
+	deleteFlattenedKeys(content, removedKeys, t.keySeparator)
+	// the lines of the file are updated in place, so comments and the order of the variables are kept
+	original := b
+	if !isDotEnv(t.path) {
+		original = nil
+	}
+	envB, err := envPatch(original, mapToStringMap(content))
 	if err != nil {
-		return err
+		return nil, err
+	}
+	err = cl.writeFile(t.outputPath, envB, t)
+	if err != nil {
+		return nil, err
 	}
-	defer fileWriteP.Close()
+	return diffChanges(originalContent, content, nil), nil
+}
 
-	// replace all content of file with merged content
-	_, err = fileWriteP.Write(mergedContentB)
+func (cl Client) properties(b []byte, t Transformer) ([]Change, error) {
+	propertiesMap, err := parsePropertiesItems(t.items)
+	if err != nil {
+		return nil, err
+	}
+	removedKeys, err := t.removedKeys(".", "")
+	if err != nil {
+		return nil, err
+	}
+	content := stringMapToMap(decodeProperties(b))
+	revertChanges(content, t.previousChanges)
+	originalContent := deepCopy(content).(map[string]interface{})
 
+	for k, v := range propertiesMap {
+		content[k] = v
+	}
+	deleteFlattenedKeys(content, removedKeys, ".")
+	// the lines of the file are updated in place, so comments and the order of the properties are kept
+	err = cl.writeFile(t.outputPath, propertiesPatch(b, mapToStringMap(content)), t)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	fileWriteP.Sync()
-	return nil
+	return diffChanges(originalContent, content, nil), nil
 }
 
-func (cl Client) dotEnv(b []byte, path, content string) error {
+// decodeFile decodes the content of a json or yaml file, an empty file is decoded as an empty map
+func decodeFile(b []byte, path string) (map[string]interface{}, error) {
+	content := map[string]interface{}{}
+	// Unmarshal empty json/map (empty byte array=>b=0) we will get 'unexpected end of JSON input' error
+	//The conditional below aims to workaround this error
+	if len(b) > 0 {
+		err := supportedFileExtDecode[filepath.Ext(path)](b, &content)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Content of file %s is malformed: %s", path, err.Error()))
+		}
+	}
+	return content, nil
+}
+
+// encodeFile encodes the content with the encoder of the file extension. When the extension supports
+// patching, the original content of the file is reused so that its layout is kept
+func encodeFile(original []byte, path string, content interface{}, indent int) ([]byte, error) {
+	ext := filepath.Ext(path)
+	if patch, ok := supportedFileExtPatch[ext]; ok && len(original) > 0 {
+		return patch(original, content)
+	}
+	if indent > 0 {
+		switch ext {
+		case ".json":
+			b, err := json.MarshalIndent(content, "", strings.Repeat(" ", indent))
+			return append(b, '\n'), err
+		case ".yaml", ".yml":
+			var buf bytes.Buffer
+			encoder := yaml.NewEncoder(&buf)
+			encoder.SetIndent(indent)
+			if err := encoder.Encode(content); err != nil {
+				return nil, err
+			}
+			err := encoder.Close()
+			return buf.Bytes(), err
+		}
+	}
+	return supportedFileExtEncode[ext](content)
+}
 
-	fileContent, err := godotenv.Unmarshal(string(b))
+// writeFile replaces the content of the file atomically: the content is written to a temporary file
+// in the same directory, synced to disk and then renamed over the file, so a failure at any point
+// leaves the original file untouched
+func (cl Client) writeFile(path string, b []byte, t Transformer) (err error) {
+	dir, base := filepath.Split(path)
+	if dir != "" {
+		if err := os.MkdirAll(dir, cl.directoryPermission(t)); err != nil {
+			return err
+		}
+	}
+
+	// existing files keep their permissions and owner unless they are set explicitly
+	mode := cl.newFilePermission(t)
+	uid, gid := -1, -1
+	if info, statErr := os.Stat(path); statErr == nil {
+		if t.filePermission == 0 {
+			mode = info.Mode().Perm()
+		}
+		uid, gid, _ = fileOwner(info)
+	}
+	ownerUid, ownerGid, err := lookupOwner(t.owner, t.group)
 	if err != nil {
 		return err
 	}
-	envMap, err := godotenv.Unmarshal(content)
+	if t.backup {
+		if err := cl.backupFile(path, b, t); err != nil {
+			return err
+		}
+	}
+
+	fileWriteP, err := createTempFile(dir, base)
 	if err != nil {
 		return err
 	}
-	// merging environment variables to map that contains provided file (.env) environment variables
-	for k, v := range envMap {
-		fileContent[k] = v
+	defer func() {
+		if err != nil {
+			fileWriteP.Close()
+			os.Remove(fileWriteP.Name())
+		}
+	}()
+
+	if err = fileWriteP.Chmod(mode); err != nil {
+		return err
 	}
-	err = godotenv.Write(fileContent, path)
-	if err != nil {
+	if ownerUid >= 0 || ownerGid >= 0 {
+		if err = fileWriteP.Chown(ownerUid, ownerGid); err != nil {
+			return err
+		}
+	}
+	// the temporary file belongs to the user running terraform, keeping the owner and the group of an existing file
+	// is best-effort as only privileged users can give a file away (a group can be kept by one of its members)
+	if info, statErr := fileWriteP.Stat(); statErr == nil {
+		tempUid, tempGid, ok := fileOwner(info)
+		if ownerUid < 0 && uid >= 0 && (!ok || tempUid != uid) {
+			if err = keepOwner(fileWriteP, uid, -1); err != nil {
+				return err
+			}
+		}
+		if ownerGid < 0 && gid >= 0 && (!ok || tempGid != gid) {
+			if err = keepOwner(fileWriteP, -1, gid); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err = fileWriteP.Write(b); err != nil {
+		return err
+	}
+	if err = fileWriteP.Sync(); err != nil {
+		return err
+	}
+	if err = fileWriteP.Close(); err != nil {
+		return err
+	}
+	return os.Rename(fileWriteP.Name(), path)
+}
+
+// keepOwner changes the owner of the file, permission errors are ignored
+func keepOwner(file *os.File, uid, gid int) error {
+	if err := file.Chown(uid, gid); err != nil && !errors.Is(err, os.ErrPermission) {
 		return err
 	}
 	return nil
 }
 
+func createTempFile(dir, base string) (*os.File, error) {
+	for i := 0; ; i++ {
+		name := filepath.Join(dir, fmt.Sprintf(".%s.%d.tmp", base, time.Now().UnixNano()))
+		file, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0777)
+		if os.IsExist(err) && i < 10 {
+			continue
+		}
+		return file, err
+	}
+}
+
+func isDotEnv(path string) bool {
+	ok, _ := regexp.MatchString(".env", filepath.Ext(path))
+	return ok
+}
+
+func stringMapToMap(values map[string]string) map[string]interface{} {
+	content := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		content[k] = v
+	}
+	return content
+}
+
+func mapToStringMap(content map[string]interface{}) map[string]string {
+	values := make(map[string]string, len(content))
+	for k, v := range content {
+		values[k] = stringValue(v)
+	}
+	return values
+}
+
+// stringValue formats the value written to the files holding strings (.env, properties, ini and xml). Numbers
+// of items are decoded as float64, they are written without exponent (`1048576` rather than `1.048576e+06`)
+func stringValue(v interface{}) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
 func (cl Client) ReadHandler(path string) (*os.File, error) {
+	return cl.openFile(path, Transformer{})
+}
+
+// openFile opens the file, the file and its directory are created when they don't exist
+func (cl Client) openFile(path string, t Transformer) (*os.File, error) {
 	dirPath, _ := filepath.Split(path)
 	// check if directory exists and create new one if not
-	if _, err := os.Stat(dirPath); os.IsNotExist(err) {
-		err := os.MkdirAll(dirPath, 0777)
-		return nil, err
-
+	if _, err := os.Stat(dirPath); dirPath != "" && os.IsNotExist(err) {
+		if err := os.MkdirAll(dirPath, cl.directoryPermission(t)); err != nil {
+			return nil, err
+		}
 	}
 
 	//If the file does not exist, a new file is created.
-	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0777)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, cl.newFilePermission(t))
 	if err != nil {
 		return nil, err
 	}
@@ -2,11 +2,15 @@ package utils
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v3"
 )
@@ -535,3 +539,983 @@ func TestEnvFileEdit(t *testing.T) {
 		}
 	})
 }
+
+//</ENV FILE>
+
+func TestRevertFileTransform(t *testing.T) {
+	t.Run("Revert merged keys of json & .env files", func(t *testing.T) {
+		testContent := []struct {
+			cl          Client
+			filePath    string
+			fileContent string
+			srcContent  string
+			decode      func(b []byte) interface{}
+		}{
+			{
+				cl:          Client{},
+				filePath:    "./test_artifact/revert-001.json",
+				fileContent: `{"coach":"Mou","club":{"stadium":"Bernabeu"},"Teams":["Roma"]}`,
+				srcContent:  `{"coach":"Zidane","club":{"name":"Real Madrid"},"Teams":["Inter"]}`,
+				decode: func(b []byte) interface{} {
+					content := map[string]interface{}{}
+					json.Unmarshal(b, &content)
+					return content
+				},
+			},
+			{
+				cl:          Client{},
+				filePath:    "./test_artifact/revert.env",
+				fileContent: "DB_HOST=localhost\nDB_PASSWORD=password\n",
+				srcContent:  "DB_PASSWORD=newpassword\nVERSION=1.1.2",
+				decode: func(b []byte) interface{} {
+					content, _ := godotenv.Unmarshal(string(b))
+					return content
+				},
+			},
+		}
+		for _, value := range testContent {
+			os.WriteFile(value.filePath, []byte(value.fileContent), 0666)
+
+			changes, err := value.cl.FileTransformChanges(value.filePath, value.srcContent, value.filePath)
+			assert.NoError(t, err)
+			// the file content is changed by the transformation and restored by the revert
+			b, _ := os.ReadFile(value.filePath)
+			assert.NotEqual(t, value.decode([]byte(value.fileContent)), value.decode(b))
+
+			err = value.cl.Revert(value.filePath, changes)
+			assert.NoError(t, err)
+			b, _ = os.ReadFile(value.filePath)
+			assert.Equal(t, value.decode([]byte(value.fileContent)), value.decode(b))
+			os.Remove(value.filePath)
+		}
+	})
+	t.Run("Revert keys removed from items on the next transformation", func(t *testing.T) {
+		cl := Client{}
+		filePath := "./test_artifact/revert-002.json"
+		os.WriteFile(filePath, []byte(`{"coach":"Mou"}`), 0666)
+
+		changes, _ := cl.FileTransformChanges(filePath, `{"coach":"Zidane","club":"Real"}`, filePath)
+		changes, _ = cl.FileTransformChanges(filePath, `{"club":"Madrid"}`, filePath, WithPreviousChanges(changes))
+		b, _ := os.ReadFile(filePath)
+		content := map[string]interface{}{}
+		json.Unmarshal(b, &content)
+		assert.Equal(t, map[string]interface{}{"coach": "Mou", "club": "Madrid"}, content)
+		assert.Equal(t, []Change{{Path: []string{"club"}, Existed: false}}, changes)
+
+		cl.Revert(filePath, changes)
+		b, _ = os.ReadFile(filePath)
+		content = map[string]interface{}{}
+		json.Unmarshal(b, &content)
+		assert.Equal(t, map[string]interface{}{"coach": "Mou"}, content)
+		os.Remove(filePath)
+	})
+}
+
+func TestCurrentItems(t *testing.T) {
+	t.Run("Report the value of managed keys found in the file", func(t *testing.T) {
+		testContent := []struct {
+			cl                 Client
+			filePath           string
+			fileContent        string
+			srcContent         string
+			overrideArrayItems bool
+			expectedItems      string
+		}{
+			// no drift, keys owned by other tools are ignored
+			{
+				cl:                 Client{},
+				filePath:           "./test_artifact/drift-001.json",
+				fileContent:        `{"coach":"Mou","club":{"name":"Real","stadium":"Bernabeu"},"Teams":["Roma","Inter"]}`,
+				srcContent:         `{"club":{"name":"Real"},"Teams":["Inter"]}`,
+				overrideArrayItems: false,
+				expectedItems:      `{"club":{"name":"Real"},"Teams":["Inter"]}`,
+			},
+			// managed values drifted or were removed
+			{
+				cl:                 Client{},
+				filePath:           "./test_artifact/drift-002.yml",
+				fileContent:        "coach: Mou\nclub:\n  stadium: Bernabeu\nTeams:\n  - Roma\n",
+				srcContent:         `{"coach":"Zidane","club":{"name":"Real"},"Teams":["Inter"]}`,
+				overrideArrayItems: false,
+				expectedItems:      `{"coach":"Mou","club":{},"Teams":["Roma"]}`,
+			},
+			{
+				cl:            Client{},
+				filePath:      "./test_artifact/drift.env",
+				fileContent:   "DB_HOST=localhost\nDB_PASSWORD=changed\n",
+				srcContent:    "DB_PASSWORD=password\nVERSION=1.1.2",
+				expectedItems: "DB_PASSWORD=changed",
+			},
+			// files holding strings report the numbers & booleans of items written the same way as no drift
+			{
+				cl:            Client{},
+				filePath:      "./test_artifact/drift-003.service",
+				fileContent:   "[Service]\nRestartSec=5\nEnabled=true\nNice=10\nCPUAffinity=1\nCPUAffinity=2\n",
+				srcContent:    `{"Service":{"RestartSec":5,"Enabled":true,"Nice":5,"CPUAffinity":[1,2]}}`,
+				expectedItems: `{"Service":{"RestartSec":5,"Enabled":true,"Nice":"10","CPUAffinity":[1,2]}}`,
+			},
+			{
+				cl:            Client{},
+				filePath:      "./test_artifact/drift-004.xml",
+				fileContent:   "<config size=\"20\">\n  <limit>1048576</limit>\n  <debug>false</debug>\n  <port>80</port>\n  <port>443</port>\n</config>\n",
+				srcContent:    `{"config":{"@size":20,"limit":1048576,"debug":true,"port":[80,443]}}`,
+				expectedItems: `{"config":{"@size":20,"limit":1048576,"debug":"false","port":[80,443]}}`,
+			},
+			{
+				cl:            Client{},
+				filePath:      "./test_artifact/drift-005.properties",
+				fileContent:   "server.port=8080\nserver.ssl=true\nserver.threads=10\n",
+				srcContent:    `{"server":{"port":8080,"ssl":true,"threads":20}}`,
+				expectedItems: `{"server":{"port":8080,"ssl":true,"threads":"10"}}`,
+			},
+			{
+				cl:            Client{},
+				filePath:      "./test_artifact/drift-006.env",
+				fileContent:   "PORT=8080\nDEBUG=true\nWORKERS=2\n",
+				srcContent:    `{"PORT":8080,"DEBUG":true,"WORKERS":4}`,
+				expectedItems: `{"PORT":8080,"DEBUG":true,"WORKERS":"2"}`,
+			},
+		}
+		for _, value := range testContent {
+			os.WriteFile(value.filePath, []byte(value.fileContent), 0666)
+
+			items, err := value.cl.CurrentItems(value.filePath, value.srcContent, WithOverrideArrayItems(value.overrideArrayItems))
+			assert.NoError(t, err)
+			assert.True(t, ItemsEqual(value.expectedItems, items), "expected %s, got %s", value.expectedItems, items)
+			os.Remove(value.filePath)
+		}
+	})
+}
+
+func TestImportItems(t *testing.T) {
+	t.Run("Seed items with the content of the file", func(t *testing.T) {
+		testContent := []struct {
+			cl            Client
+			filePath      string
+			fileContent   string
+			keyPath       []string
+			expectedItems string
+			expectedError string
+		}{
+			{
+				cl:            Client{},
+				filePath:      "./test_artifact/import-001.yml",
+				fileContent:   "coach: Mou\nclub:\n  stadium: Bernabeu\n  name: Real\n",
+				expectedItems: `{"coach":"Mou","club":{"stadium":"Bernabeu","name":"Real"}}`,
+			},
+			{
+				cl:            Client{},
+				filePath:      "./test_artifact/import-001.yml",
+				fileContent:   "coach: Mou\nclub:\n  stadium: Bernabeu\n  name: Real\n",
+				keyPath:       []string{"club", "name"},
+				expectedItems: `{"club":{"name":"Real"}}`,
+			},
+			{
+				cl:            Client{},
+				filePath:      "./test_artifact/import-001.yml",
+				fileContent:   "coach: Mou\n",
+				keyPath:       []string{"coach", "name"},
+				expectedError: "Key coach.name does not exist in file ./test_artifact/import-001.yml",
+			},
+			{
+				cl:            Client{},
+				filePath:      "./test_artifact/import.env",
+				fileContent:   "DB_HOST=localhost\nDB_PASSWORD=password\n",
+				keyPath:       []string{"DB_PASSWORD"},
+				expectedItems: "DB_PASSWORD=password",
+			},
+		}
+		for _, value := range testContent {
+			os.WriteFile(value.filePath, []byte(value.fileContent), 0666)
+
+			items, err := value.cl.ImportItems(value.filePath, value.keyPath)
+			if value.expectedError != "" {
+				assert.ErrorContains(t, err, value.expectedError)
+			} else {
+				assert.True(t, ItemsEqual(value.expectedItems, items), "expected %s, got %s", value.expectedItems, items)
+			}
+			os.Remove(value.filePath)
+		}
+	})
+}
+
+func TestTomlFileTransform(t *testing.T) {
+	t.Run("Merge items in toml file", func(t *testing.T) {
+		fileContent := `[package]
+name = "demo"
+version = "0.1.0"
+
+[[bin]]
+name = "server"
+path = "src/server.rs"
+
+[release]
+date = 1979-05-27T07:32:00-08:00
+day = 1979-05-27
+`
+		testContent := []struct {
+			cl                 Client
+			srcContent         string
+			filePath           string
+			overrideArrayItems bool
+			expectedOutcome    map[string]interface{}
+		}{
+			{
+				cl:                 Client{},
+				srcContent:         `{"package":{"version":"0.2.0"},"profile":{"release":{"opt-level":3}},"bin":[{"name":"cli"}]}`,
+				filePath:           "./test_artifact/Cargo-001.toml",
+				overrideArrayItems: false,
+				expectedOutcome: map[string]interface{}{
+					"package": map[string]interface{}{"name": "demo", "version": "0.2.0"},
+					"profile": map[string]interface{}{"release": map[string]interface{}{"opt-level": int64(3)}},
+					"bin": []interface{}{
+						map[string]interface{}{"name": "server", "path": "src/server.rs"},
+						map[string]interface{}{"name": "cli"},
+					},
+					"release": map[string]interface{}{
+						"date": time.Date(1979, 5, 27, 7, 32, 0, 0, time.FixedZone("", -8*60*60)),
+						"day":  toml.LocalDate{Year: 1979, Month: 5, Day: 27},
+					},
+				},
+			},
+			{
+				cl:                 Client{},
+				srcContent:         `{"bin":[{"name":"cli"}]}`,
+				filePath:           "./test_artifact/Cargo-002.toml",
+				overrideArrayItems: true,
+				expectedOutcome: map[string]interface{}{
+					"package": map[string]interface{}{"name": "demo", "version": "0.1.0"},
+					"bin":     []interface{}{map[string]interface{}{"name": "cli"}},
+					"release": map[string]interface{}{
+						"date": time.Date(1979, 5, 27, 7, 32, 0, 0, time.FixedZone("", -8*60*60)),
+						"day":  toml.LocalDate{Year: 1979, Month: 5, Day: 27},
+					},
+				},
+			},
+		}
+		for _, value := range testContent {
+			os.WriteFile(value.filePath, []byte(fileContent), 0666)
+
+			err := value.cl.FileTransform(value.filePath, value.srcContent, value.filePath, WithOverrideArrayItems(value.overrideArrayItems))
+			assert.NoError(t, err)
+			actualFileContentInBytes, _ := os.ReadFile(value.filePath)
+			actualFileContent := map[string]interface{}{}
+			toml.Unmarshal(actualFileContentInBytes, &actualFileContent)
+			assert.Equal(t, value.expectedOutcome, actualFileContent)
+			os.Remove(value.filePath)
+		}
+	})
+	t.Run("Convert toml file to json", func(t *testing.T) {
+		cl := Client{}
+		filePath := "./test_artifact/pyproject.toml"
+		outputPath := "./test_artifact/pyproject.json"
+		os.WriteFile(filePath, []byte("[project]\nname = \"demo\"\n"), 0666)
+
+		err := cl.FileTransform(filePath, `{"project":{"version":"1.0.0"}}`, outputPath)
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ := os.ReadFile(outputPath)
+		actualFileContent := map[string]interface{}{}
+		json.Unmarshal(actualFileContentInBytes, &actualFileContent)
+		assert.Equal(t, map[string]interface{}{"project": map[string]interface{}{"name": "demo", "version": "1.0.0"}}, actualFileContent)
+		os.Remove(filePath)
+		os.Remove(outputPath)
+	})
+}
+
+func TestIniFileTransform(t *testing.T) {
+	t.Run("Merge items in the sections of systemd unit file", func(t *testing.T) {
+		fileContent := "[Unit]\nDescription=My service\n\n[Service]\nExecStartPre=/bin/a\nExecStart=/usr/bin/app\n"
+		testContent := []struct {
+			cl                 Client
+			srcContent         string
+			filePath           string
+			overrideArrayItems bool
+			expectedContent    string
+		}{
+			{
+				cl:                 Client{},
+				srcContent:         `{"Service":{"Restart":"always","ExecStartPre":["/bin/b"]}}`,
+				filePath:           "./test_artifact/app-001.service",
+				overrideArrayItems: false,
+				expectedContent:    "[Unit]\nDescription=My service\n\n[Service]\nExecStartPre=/bin/a\nExecStartPre=/bin/b\nExecStart=/usr/bin/app\nRestart=always\n",
+			},
+			{
+				cl:                 Client{},
+				srcContent:         `{"Service":{"Restart":"always","ExecStartPre":["/bin/b"]}}`,
+				filePath:           "./test_artifact/app-002.service",
+				overrideArrayItems: true,
+				expectedContent:    "[Unit]\nDescription=My service\n\n[Service]\nExecStartPre=/bin/b\nExecStart=/usr/bin/app\nRestart=always\n",
+			},
+		}
+		for _, value := range testContent {
+			os.WriteFile(value.filePath, []byte(fileContent), 0666)
+
+			err := value.cl.FileTransform(value.filePath, value.srcContent, value.filePath, WithOverrideArrayItems(value.overrideArrayItems))
+			assert.NoError(t, err)
+			actualFileContentInBytes, _ := os.ReadFile(value.filePath)
+			assert.Equal(t, value.expectedContent, string(actualFileContentInBytes))
+			os.Remove(value.filePath)
+		}
+	})
+	t.Run("Write numbers of items without exponent", func(t *testing.T) {
+		filePath := "./test_artifact/limits.service"
+		os.WriteFile(filePath, []byte("[Service]\nExecStart=/usr/bin/app\n"), 0666)
+
+		err := Client{}.FileTransform(filePath, `{"Service":{"LimitNOFILE":1048576,"CPUWeight":0.5}}`, filePath)
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ := os.ReadFile(filePath)
+		assert.Equal(t, "[Service]\nExecStart=/usr/bin/app\nCPUWeight=0.5\nLimitNOFILE=1048576\n", string(actualFileContentInBytes))
+		os.Remove(filePath)
+	})
+}
+
+func TestXmlFileTransform(t *testing.T) {
+	t.Run("Merge items in xml file & convert it to json", func(t *testing.T) {
+		cl := Client{}
+		filePath := "./test_artifact/pom.xml"
+		outputPath := "./test_artifact/pom.json"
+		os.WriteFile(filePath, []byte("<project>\n  <artifactId>demo</artifactId>\n</project>\n"), 0666)
+
+		err := cl.FileTransform(filePath, `{"project":{"version":"1.0.0"}}`, filePath)
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ := os.ReadFile(filePath)
+		assert.Equal(t, "<project>\n  <artifactId>demo</artifactId>\n  <version>1.0.0</version>\n</project>\n", string(actualFileContentInBytes))
+
+		err = cl.FileTransform(filePath, `{"project":{"@xmlns":"http://maven.apache.org/POM/4.0.0"}}`, outputPath)
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ = os.ReadFile(outputPath)
+		actualFileContent := map[string]interface{}{}
+		json.Unmarshal(actualFileContentInBytes, &actualFileContent)
+		assert.Equal(t, map[string]interface{}{
+			"project": map[string]interface{}{
+				"@xmlns":     "http://maven.apache.org/POM/4.0.0",
+				"artifactId": "demo",
+				"version":    "1.0.0",
+			},
+		}, actualFileContent)
+		os.Remove(filePath)
+		os.Remove(outputPath)
+	})
+	t.Run("Merge arrays in elements defined once", func(t *testing.T) {
+		filePath := "./test_artifact/pom-single.xml"
+		fileContent := "<project>\n  <dependencies>\n    <dependency>\n      <artifactId>junit</artifactId>\n    </dependency>\n  </dependencies>\n</project>\n"
+		srcContent := `{"project":{"dependencies":{"dependency":[{"artifactId":"mockito"}]}}}`
+		testContent := []struct {
+			overrideArrayItems bool
+			expectedContent    string
+		}{
+			{
+				overrideArrayItems: false,
+				expectedContent:    "<project>\n  <dependencies>\n    <dependency>\n      <artifactId>junit</artifactId>\n    </dependency>\n    <dependency>\n      <artifactId>mockito</artifactId>\n    </dependency>\n  </dependencies>\n</project>\n",
+			},
+			{
+				overrideArrayItems: true,
+				expectedContent:    "<project>\n  <dependencies>\n    <dependency>\n      <artifactId>mockito</artifactId>\n    </dependency>\n  </dependencies>\n</project>\n",
+			},
+		}
+		for _, value := range testContent {
+			os.WriteFile(filePath, []byte(fileContent), 0666)
+
+			err := Client{}.FileTransform(filePath, srcContent, filePath, WithOverrideArrayItems(value.overrideArrayItems))
+			assert.NoError(t, err)
+			actualFileContentInBytes, _ := os.ReadFile(filePath)
+			assert.Equal(t, value.expectedContent, string(actualFileContentInBytes))
+		}
+		os.WriteFile(filePath, []byte(fileContent), 0666)
+		err := Client{}.FileTransform(filePath, srcContent, filePath, WithArrayItemsStrategy(ArrayUnion))
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ := os.ReadFile(filePath)
+		assert.Equal(t, testContent[0].expectedContent, string(actualFileContentInBytes))
+		current, err := Client{}.CurrentItems(filePath, srcContent, WithArrayItemsStrategy(ArrayUnion))
+		assert.NoError(t, err)
+		assert.True(t, ItemsEqual(srcContent, current), current)
+		os.Remove(filePath)
+	})
+	t.Run("Write numbers of items without exponent", func(t *testing.T) {
+		filePath := "./test_artifact/limits.xml"
+		os.WriteFile(filePath, []byte("<config>\n  <name>app</name>\n</config>\n"), 0666)
+
+		err := Client{}.FileTransform(filePath, `{"config":{"@size":20000000,"limit":1048576}}`, filePath)
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ := os.ReadFile(filePath)
+		assert.Equal(t, "<config size=\"20000000\">\n  <name>app</name>\n  <limit>1048576</limit>\n</config>\n", string(actualFileContentInBytes))
+		os.Remove(filePath)
+	})
+}
+
+func TestPropertiesFileTransform(t *testing.T) {
+	t.Run("Upsert properties & revert them", func(t *testing.T) {
+		cl := Client{}
+		filePath := "./test_artifact/application.properties"
+		fileContent := "# datasource\nspring.datasource.url=jdbc:h2:mem\nserver.port: 8080\n"
+		os.WriteFile(filePath, []byte(fileContent), 0666)
+
+		changes, err := cl.FileTransformChanges(filePath, `{"spring":{"datasource":{"username":"app"}},"server":{"port":9090}}`, filePath)
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ := os.ReadFile(filePath)
+		assert.Equal(t, "# datasource\nspring.datasource.url=jdbc:h2:mem\nserver.port: 9090\nspring.datasource.username=app\n", string(actualFileContentInBytes))
+
+		err = cl.FileTransform(filePath, "kafka.bootstrap.servers=localhost:9092", filePath)
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ = os.ReadFile(filePath)
+		assert.Equal(t, "# datasource\nspring.datasource.url=jdbc:h2:mem\nserver.port: 9090\nspring.datasource.username=app\nkafka.bootstrap.servers=localhost:9092\n", string(actualFileContentInBytes))
+
+		err = cl.Revert(filePath, changes)
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ = os.ReadFile(filePath)
+		assert.Equal(t, "# datasource\nspring.datasource.url=jdbc:h2:mem\nserver.port: 8080\nkafka.bootstrap.servers=localhost:9092\n", string(actualFileContentInBytes))
+
+		current, err := cl.CurrentItems(filePath, `{"server":{"port":9090}}`)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"server":{"port":"8080"}}`, current)
+
+		err = cl.FileTransform(filePath, `{"a":"b"}`, "./test_artifact/application.json")
+		assert.Error(t, err)
+		os.Remove(filePath)
+	})
+}
+
+func TestHclFileTransform(t *testing.T) {
+	t.Run("Merge items in tfvars file & convert it to tfvars.json", func(t *testing.T) {
+		cl := Client{}
+		filePath := "./test_artifact/terraform.tfvars"
+		outputPath := "./test_artifact/terraform.tfvars.json"
+		os.WriteFile(filePath, []byte("# managed by the network stack\nregion = \"eu-west-1\"\ntags = {\n  team = \"platform\"\n}\n"), 0666)
+
+		err := cl.FileTransform(filePath, `{"tags":{"env":"prod"},"subnets":["10.0.1.0/24"]}`, filePath)
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ := os.ReadFile(filePath)
+		assert.Equal(t, "# managed by the network stack\nregion = \"eu-west-1\"\ntags = {\n  env  = \"prod\"\n  team = \"platform\"\n}\nsubnets = [\"10.0.1.0/24\"]\n", string(actualFileContentInBytes))
+
+		err = cl.FileTransform(filePath, `{"region":"us-east-1"}`, outputPath)
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ = os.ReadFile(outputPath)
+		actualFileContent := map[string]interface{}{}
+		json.Unmarshal(actualFileContentInBytes, &actualFileContent)
+		assert.Equal(t, map[string]interface{}{
+			"region":  "us-east-1",
+			"subnets": []interface{}{"10.0.1.0/24"},
+			"tags":    map[string]interface{}{"env": "prod", "team": "platform"},
+		}, actualFileContent)
+		os.Remove(filePath)
+		os.Remove(outputPath)
+	})
+}
+
+func TestConvertFileEnv(t *testing.T) {
+	t.Run("Write .env file to the output file", func(t *testing.T) {
+		cl := Client{}
+		filePath := "./test_artifact/convert.env"
+		outputPath := "./test_artifact/convert-output.env"
+		os.WriteFile(filePath, []byte("# app\nPORT=80\n"), 0666)
+
+		err := cl.FileTransform(filePath, "DEBUG=true", outputPath)
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ := os.ReadFile(filePath)
+		assert.Equal(t, "# app\nPORT=80\n", string(actualFileContentInBytes))
+		actualFileContentInBytes, _ = os.ReadFile(outputPath)
+		assert.Equal(t, "# app\nPORT=80\nDEBUG=\"true\"\n", string(actualFileContentInBytes))
+		os.Remove(filePath)
+		os.Remove(outputPath)
+	})
+	t.Run("Convert .env file to nested json & yaml", func(t *testing.T) {
+		cl := Client{}
+		filePath := "./test_artifact/nested.env"
+		os.WriteFile(filePath, []byte("APP_DB__HOST=localhost\nAPP_DB__PORT=5432\nOTHER=1\n"), 0666)
+		testContent := []struct {
+			outputPath string
+			decode     Unmarshal
+		}{
+			{outputPath: "./test_artifact/nested-env.json", decode: json.Unmarshal},
+			{outputPath: "./test_artifact/nested-env.yaml", decode: yaml.Unmarshal},
+		}
+		for _, value := range testContent {
+			err := cl.FileTransform(filePath, "APP_LOG__LEVEL=debug", value.outputPath, WithKeyPrefix("APP_"))
+			assert.NoError(t, err)
+			actualFileContentInBytes, _ := os.ReadFile(value.outputPath)
+			actualFileContent := map[string]interface{}{}
+			value.decode(actualFileContentInBytes, &actualFileContent)
+			assert.Equal(t, map[string]interface{}{
+				"DB":  map[string]interface{}{"HOST": "localhost", "PORT": "5432"},
+				"LOG": map[string]interface{}{"LEVEL": "debug"},
+			}, actualFileContent)
+
+			current, err := cl.CurrentItems(value.outputPath, "APP_LOG__LEVEL=debug", WithKeyPrefix("APP_"))
+			assert.NoError(t, err)
+			assert.Equal(t, `APP_LOG__LEVEL="debug"`, current)
+			os.Remove(value.outputPath)
+		}
+		os.Remove(filePath)
+	})
+	t.Run("Convert nested json file to .env file", func(t *testing.T) {
+		cl := Client{}
+		filePath := "./test_artifact/nested.json"
+		outputPath := "./test_artifact/nested-json.env"
+		os.WriteFile(filePath, []byte(`{"db":{"host":"localhost","port":5432},"hosts":["a","b"]}`), 0666)
+
+		err := cl.FileTransform(filePath, `{"db":{"user":"app"}}`, outputPath, WithKeySeparator("_"), WithKeyPrefix("APP_"))
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ := os.ReadFile(outputPath)
+		assert.Equal(t, "APP_db_host=\"localhost\"\nAPP_db_port=5432\nAPP_db_user=\"app\"\nAPP_hosts[0]=\"a\"\nAPP_hosts[1]=\"b\"\n", string(actualFileContentInBytes))
+
+		current, err := cl.CurrentItems(outputPath, `{"db":{"user":"admin"}}`, WithKeySeparator("_"), WithKeyPrefix("APP_"))
+		assert.NoError(t, err)
+		assert.Equal(t, `{"db":{"user":"app"}}`, current)
+		os.Remove(filePath)
+		os.Remove(outputPath)
+	})
+}
+
+func TestAtomicWrite(t *testing.T) {
+	t.Run("Keep permissions & leave no temporary files", func(t *testing.T) {
+		cl := Client{}
+		dir := "./test_artifact/atomic"
+		filePath := filepath.Join(dir, "config.json")
+		os.MkdirAll(dir, 0777)
+		os.WriteFile(filePath, []byte(`{"a":1}`), 0640)
+		os.Chmod(filePath, 0640)
+
+		err := cl.FileTransform(filePath, `{"b":2}`, filePath)
+		assert.NoError(t, err)
+		info, _ := os.Stat(filePath)
+		assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+		entries, _ := os.ReadDir(dir)
+		assert.Len(t, entries, 1)
+		os.RemoveAll(dir)
+	})
+	t.Run("Keep the original file when the content can't be encoded", func(t *testing.T) {
+		cl := Client{}
+		filePath := "./test_artifact/atomic.ini"
+		os.WriteFile(filePath, []byte("[server]\nport=80\n"), 0666)
+
+		err := cl.FileTransform(filePath, `{"server":{"ports":{"http":80}}}`, filePath)
+		assert.Error(t, err)
+		actualFileContentInBytes, _ := os.ReadFile(filePath)
+		assert.Equal(t, "[server]\nport=80\n", string(actualFileContentInBytes))
+		os.Remove(filePath)
+	})
+	t.Run("Remove the temporary file when the rename fails", func(t *testing.T) {
+		dir := "./test_artifact/atomic-dir"
+		os.MkdirAll(filepath.Join(dir, "target"), 0777)
+
+		err := Client{}.writeFile(filepath.Join(dir, "target"), []byte("content"), Transformer{})
+		assert.Error(t, err)
+		entries, _ := os.ReadDir(dir)
+		assert.Len(t, entries, 1)
+		os.RemoveAll(dir)
+	})
+}
+
+func TestFilePermission(t *testing.T) {
+	t.Run("Create files & directories with the configured permissions", func(t *testing.T) {
+		dir := "./test_artifact/permission"
+		filePath := "./test_artifact/permission-source.json"
+		outputPath := filepath.Join(dir, "nested", ".env")
+		os.WriteFile(filePath, []byte(`{"a":"b"}`), 0666)
+
+		cl := Client{FilePermission: 0640, DirectoryPermission: 0750}
+		err := cl.FileTransform(filePath, `{"secret":"s3cr3t"}`, outputPath, WithFilePermission(0600))
+		assert.NoError(t, err)
+		info, _ := os.Stat(outputPath)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+		info, _ = os.Stat(filepath.Join(dir, "nested"))
+		assert.Equal(t, os.FileMode(0750), info.Mode().Perm())
+
+		otherOutputPath := filepath.Join(dir, "other.yaml")
+		err = cl.FileTransform(filePath, `{"c":"d"}`, otherOutputPath)
+		assert.NoError(t, err)
+		info, _ = os.Stat(otherOutputPath)
+		assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+		// the permissions are enforced on existing files when they are set explicitly
+		err = cl.FileTransform(filePath, `{"c":"e"}`, otherOutputPath, WithFilePermission(0600))
+		assert.NoError(t, err)
+		info, _ = os.Stat(otherOutputPath)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+		err = cl.FileTransform(filePath, `{"c":"f"}`, otherOutputPath, WithOwner(strconv.Itoa(os.Getuid()), strconv.Itoa(os.Getgid())))
+		assert.NoError(t, err)
+		os.Remove(filePath)
+		os.RemoveAll(dir)
+	})
+}
+
+func TestBackup(t *testing.T) {
+	t.Run("Save the previous content & keep the most recent backups", func(t *testing.T) {
+		dir := "./test_artifact/backup"
+		backupDir := "./test_artifact/backup/history"
+		filePath := filepath.Join(dir, "config.json")
+		os.MkdirAll(dir, 0777)
+		os.WriteFile(filePath, []byte(`{"version":0}`), 0600)
+		os.Chmod(filePath, 0600)
+
+		cl := Client{BackupDir: backupDir}
+		for i := 1; i <= 3; i++ {
+			err := cl.FileTransform(filePath, fmt.Sprintf(`{"version":%d}`, i), filePath, WithBackup(true, 2))
+			assert.NoError(t, err)
+		}
+		// the content didn't change, so no backup is taken
+		err := cl.FileTransform(filePath, `{"version":3}`, filePath, WithBackup(true, 2))
+		assert.NoError(t, err)
+
+		backups, err := cl.Backups(filePath)
+		assert.NoError(t, err)
+		assert.Len(t, backups, 2)
+		latest, err := cl.LatestBackup(filePath)
+		assert.NoError(t, err)
+		assert.Equal(t, backups[1], latest)
+		canonicalDir, _ := canonicalPath(dir)
+		assert.Equal(t, filepath.Join(backupDir, canonicalDir), filepath.Dir(latest))
+		latestContent, _ := os.ReadFile(latest)
+		assert.Equal(t, `{"version":2}`, string(latestContent))
+		info, _ := os.Stat(latest)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+		noBackups, err := Client{}.Backups(filePath)
+		assert.NoError(t, err)
+		assert.Empty(t, noBackups)
+		os.RemoveAll(dir)
+	})
+	t.Run("Keep the backups of files sharing the same name apart", func(t *testing.T) {
+		dir := "./test_artifact/backup-names"
+		cl := Client{BackupDir: filepath.Join(dir, "history")}
+		filePaths := []string{filepath.Join(dir, "api", "config.json"), filepath.Join(dir, "web", "config.json")}
+		for _, filePath := range filePaths {
+			os.MkdirAll(filepath.Dir(filePath), 0777)
+			os.WriteFile(filePath, []byte(`{"version":0}`), 0666)
+		}
+		for i, filePath := range filePaths {
+			err := cl.FileTransform(filePath, fmt.Sprintf(`{"version":%d}`, i+1), filePath, WithBackup(true, 1))
+			assert.NoError(t, err)
+			err = cl.FileTransform(filePath, fmt.Sprintf(`{"version":%d}`, i+2), filePath, WithBackup(true, 1))
+			assert.NoError(t, err)
+		}
+		for i, filePath := range filePaths {
+			backups, err := cl.Backups(filePath)
+			assert.NoError(t, err)
+			assert.Len(t, backups, 1)
+			latest, err := cl.LatestBackup(filePath)
+			assert.NoError(t, err)
+			latestContent, _ := os.ReadFile(latest)
+			assert.Equal(t, fmt.Sprintf(`{"version":%d}`, i+1), string(latestContent))
+		}
+		os.RemoveAll(dir)
+	})
+}
+
+func TestClientDefaults(t *testing.T) {
+	t.Run("Resolve paths against the base directory & use the client defaults", func(t *testing.T) {
+		dir := "./test_artifact/base-dir"
+		os.MkdirAll(dir, 0777)
+		os.WriteFile(filepath.Join(dir, "source.yaml"), []byte("players:\n  - Nacho\n"), 0666)
+
+		cl := Client{BaseDir: dir, AllowedPaths: []string{"."}, OverrideArrayItems: true, Indent: 2}
+		err := cl.FileTransform("source.yaml", `{"players":["Alaba"]}`, "output.json")
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ := os.ReadFile(filepath.Join(dir, "output.json"))
+		assert.Equal(t, "{\n  \"players\": [\n    \"Alaba\"\n  ]\n}\n", string(actualFileContentInBytes))
+
+		err = cl.FileTransform("source.yaml", `{"players":["Alaba"]}`, "output.json", WithOverrideArrayItems(false))
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ = os.ReadFile(filepath.Join(dir, "output.json"))
+		assert.Equal(t, "{\n  \"players\": [\n    \"Nacho\",\n    \"Alaba\"\n  ]\n}\n", string(actualFileContentInBytes))
+
+		err = cl.FileTransform("source.yaml", `{"a":"b"}`, "../outside.json")
+		assert.Error(t, err)
+		assert.NoFileExists(t, "./test_artifact/outside.json")
+		os.RemoveAll(dir)
+	})
+}
+
+func TestSymlinkPolicy(t *testing.T) {
+	dir := "./test_artifact/symlink"
+	setup := func() {
+		os.RemoveAll(dir)
+		os.MkdirAll(filepath.Join(dir, "project"), 0777)
+		os.MkdirAll(filepath.Join(dir, "outside"), 0777)
+		os.WriteFile(filepath.Join(dir, "outside", "target.json"), []byte(`{"a":"b"}`), 0666)
+		os.Symlink("../outside/target.json", filepath.Join(dir, "project", "link.json"))
+		os.Symlink("../outside", filepath.Join(dir, "project", "outside-dir"))
+	}
+	t.Run("Write the file the link points to when links are followed", func(t *testing.T) {
+		setup()
+		cl := Client{BaseDir: dir}
+		err := cl.FileTransform("project/link.json", `{"c":"d"}`, "project/link.json")
+		assert.NoError(t, err)
+		info, _ := os.Lstat(filepath.Join(dir, "project", "link.json"))
+		assert.True(t, info.Mode()&os.ModeSymlink != 0)
+		actualFileContentInBytes, _ := os.ReadFile(filepath.Join(dir, "outside", "target.json"))
+		assert.Equal(t, `{"a":"b","c":"d"}`, string(actualFileContentInBytes))
+	})
+	t.Run("Return error when links are refused", func(t *testing.T) {
+		setup()
+		cl := Client{BaseDir: dir, SymlinkPolicy: SymlinkRefuse}
+		err := cl.FileTransform("project/link.json", `{"c":"d"}`, "project/link.json")
+		assert.ErrorContains(t, err, "is a symbolic link")
+		actualFileContentInBytes, _ := os.ReadFile(filepath.Join(dir, "outside", "target.json"))
+		assert.Equal(t, `{"a":"b"}`, string(actualFileContentInBytes))
+	})
+	t.Run("Replace the link by a regular file", func(t *testing.T) {
+		setup()
+		os.WriteFile(filepath.Join(dir, "project", "target.json"), []byte(`{"a":"b"}`), 0666)
+		os.Symlink("target.json", filepath.Join(dir, "project", "inner-link.json"))
+		testContent := map[string]Client{
+			"project/link.json":       {BaseDir: dir, SymlinkPolicy: SymlinkReplace},
+			"project/inner-link.json": {BaseDir: dir, SymlinkPolicy: SymlinkReplace, AllowedPaths: []string{"project"}},
+		}
+		for path, cl := range testContent {
+			err := cl.FileTransform(path, `{"c":"d"}`, path)
+			assert.NoError(t, err, path)
+			info, _ := os.Lstat(filepath.Join(dir, path))
+			assert.True(t, info.Mode().IsRegular(), path)
+			actualFileContentInBytes, _ := os.ReadFile(filepath.Join(dir, path))
+			assert.Equal(t, `{"a":"b","c":"d"}`, string(actualFileContentInBytes), path)
+		}
+		actualFileContentInBytes, _ := os.ReadFile(filepath.Join(dir, "outside", "target.json"))
+		assert.Equal(t, `{"a":"b"}`, string(actualFileContentInBytes))
+		actualFileContentInBytes, _ = os.ReadFile(filepath.Join(dir, "project", "target.json"))
+		assert.Equal(t, `{"a":"b"}`, string(actualFileContentInBytes))
+	})
+	t.Run("Return error when the replaced link points outside of the allowed paths", func(t *testing.T) {
+		setup()
+		cl := Client{BaseDir: dir, SymlinkPolicy: SymlinkReplace, AllowedPaths: []string{"project"}}
+		err := cl.FileTransform("project/link.json", `{"c":"d"}`, "project/link.json")
+		assert.ErrorContains(t, err, "is not allowed")
+		info, _ := os.Lstat(filepath.Join(dir, "project", "link.json"))
+		assert.True(t, info.Mode()&os.ModeSymlink != 0)
+	})
+	t.Run("Return error when links point outside of the allowed paths", func(t *testing.T) {
+		setup()
+		cl := Client{BaseDir: dir, AllowedPaths: []string{"project"}}
+		testContent := []string{"project/link.json", "project/outside-dir/target.json", "project/outside-dir/new.json"}
+		for _, path := range testContent {
+			err := cl.FileTransform(path, `{"c":"d"}`, path)
+			assert.ErrorContains(t, err, "is not allowed", path)
+		}
+		assert.NoFileExists(t, filepath.Join(dir, "outside", "new.json"))
+		actualFileContentInBytes, _ := os.ReadFile(filepath.Join(dir, "outside", "target.json"))
+		assert.Equal(t, `{"a":"b"}`, string(actualFileContentInBytes))
+	})
+	os.RemoveAll(dir)
+}
+
+func TestMergePatchFileTransform(t *testing.T) {
+	dir := "./test_artifact/merge-patch"
+	os.MkdirAll(dir, 0777)
+	t.Run("Remove keys set to null in json file & revert the removal", func(t *testing.T) {
+		filePath := filepath.Join(dir, "config.json")
+		os.WriteFile(filePath, []byte(`{"legacy":{"url":"http://old","timeout":5},"name":"app","teams":["Roma"]}`), 0666)
+		items := `{"legacy":{"url":null},"teams":["Inter"],"debug":null}`
+
+		changes, err := Client{}.FileTransformChanges(filePath, items, filePath, WithMergeStrategy(MergePatchStrategy), WithOverrideArrayItems(false))
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ := os.ReadFile(filePath)
+		assert.Equal(t, `{"legacy":{"timeout":5},"name":"app","teams":["Inter"]}`, string(actualFileContentInBytes))
+
+		current, err := Client{}.CurrentItems(filePath, items, WithMergeStrategy(MergePatchStrategy))
+		assert.NoError(t, err)
+		assert.True(t, ItemsEqual(items, current), current)
+
+		err = Client{}.Revert(filePath, changes)
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ = os.ReadFile(filePath)
+		assert.Equal(t, `{"legacy":{"timeout":5,"url":"http://old"},"name":"app","teams":["Roma"]}`, string(actualFileContentInBytes))
+	})
+	t.Run("Remove variables set to null in .env file", func(t *testing.T) {
+		filePath := filepath.Join(dir, "app.env")
+		os.WriteFile(filePath, []byte("# database\nDB__HOST=localhost\nDB__PORT=5432\nNAME=app\n"), 0666)
+		items := `{"DB":null,"NAME":"api"}`
+
+		_, err := Client{}.FileTransformChanges(filePath, items, filePath, WithMergeStrategy(MergePatchStrategy))
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ := os.ReadFile(filePath)
+		assert.Equal(t, "# database\nNAME=api\n", string(actualFileContentInBytes))
+
+		current, err := Client{}.CurrentItems(filePath, items, WithMergeStrategy(MergePatchStrategy))
+		assert.NoError(t, err)
+		assert.True(t, ItemsEqual(items, current), current)
+	})
+	os.RemoveAll(dir)
+}
+
+func TestPatchFileTransform(t *testing.T) {
+	dir := "./test_artifact/json-patch"
+	os.MkdirAll(dir, 0777)
+	t.Run("Apply the patch to yaml file after merging items & revert it", func(t *testing.T) {
+		filePath := filepath.Join(dir, "values.yaml")
+		original := "replicas: 1\nimage:\n    tag: v1\nargs:\n    - --verbose\n    - --port=80\n"
+		os.WriteFile(filePath, []byte(original), 0666)
+		patch := `[{"op":"test","path":"/image/tag","value":"v2"},{"op":"add","path":"/args/1","value":"--debug"},{"op":"remove","path":"/replicas"}]`
+
+		changes, err := Client{}.FileTransformChanges(filePath, `{"image":{"tag":"v2"}}`, filePath, WithPatch(patch))
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ := os.ReadFile(filePath)
+		assert.Equal(t, "image:\n    tag: v2\nargs:\n    - --verbose\n    - --debug\n    - --port=80\n", string(actualFileContentInBytes))
+
+		// keys touched by the previous apply are restored before the patch is applied again
+		changes, err = Client{}.FileTransformChanges(filePath, `{"image":{"tag":"v2"}}`, filePath, WithPatch(patch), WithPreviousChanges(changes))
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ = os.ReadFile(filePath)
+		assert.Equal(t, "image:\n    tag: v2\nargs:\n    - --verbose\n    - --debug\n    - --port=80\n", string(actualFileContentInBytes))
+
+		err = Client{}.Revert(filePath, changes)
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ = os.ReadFile(filePath)
+		assert.Equal(t, "image:\n    tag: v1\nargs:\n    - --verbose\n    - --port=80\nreplicas: 1\n", string(actualFileContentInBytes))
+	})
+	t.Run("Return error and keep the file when a test operation does not match", func(t *testing.T) {
+		filePath := filepath.Join(dir, "config.json")
+		os.WriteFile(filePath, []byte(`{"version":"1.0"}`), 0666)
+		patch := `[{"op":"test","path":"/version","value":"2.0"},{"op":"replace","path":"/version","value":"3.0"}]`
+		_, err := Client{}.FileTransformChanges(filePath, "", filePath, WithPatch(patch))
+		assert.ErrorContains(t, err, `value of /version is "1.0", expected "2.0"`)
+		actualFileContentInBytes, _ := os.ReadFile(filePath)
+		assert.Equal(t, `{"version":"1.0"}`, string(actualFileContentInBytes))
+	})
+	os.RemoveAll(dir)
+}
+
+func TestArrayMergeKeysFileTransform(t *testing.T) {
+	t.Run("Merge containers by name in yaml file without duplicating them", func(t *testing.T) {
+		filePath := "./test_artifact/deployment.yaml"
+		os.WriteFile(filePath, []byte("spec:\n    containers:\n        - name: api\n          image: api:v1\n        - name: db\n          image: postgres\n"), 0666)
+		items := `{"spec":{"containers":[{"name":"api","image":"api:v2"},{"name":"sidecar","image":"envoy"}]}}`
+		options := []func(*Transformer){WithOverrideArrayItems(false), WithArrayMergeKeys(map[string]string{"spec.containers": "name"})}
+
+		for i := 0; i < 2; i++ {
+			err := Client{}.FileTransform(filePath, items, filePath, options...)
+			assert.NoError(t, err)
+		}
+		actualFileContentInBytes, _ := os.ReadFile(filePath)
+		assert.Equal(t, "spec:\n    containers:\n        - name: api\n          image: api:v2\n        - name: db\n          image: postgres\n        - image: envoy\n          name: sidecar\n", string(actualFileContentInBytes))
+
+		current, err := Client{}.CurrentItems(filePath, items, options...)
+		assert.NoError(t, err)
+		assert.True(t, ItemsEqual(items, current), current)
+		os.Remove(filePath)
+	})
+}
+
+func TestArrayUnionFileTransform(t *testing.T) {
+	t.Run("Apply the same items twice without duplicating array elements", func(t *testing.T) {
+		filePath := "./test_artifact/compose-union.yml"
+		os.WriteFile(filePath, []byte("services:\n    api:\n        environment:\n            - PORT=80\n"), 0666)
+		items := `{"services":{"api":{"environment":["DEBUG=1","PORT=80"]}}}`
+
+		for i := 0; i < 2; i++ {
+			err := Client{}.FileTransform(filePath, items, filePath, WithArrayItemsStrategy(ArrayUnion))
+			assert.NoError(t, err)
+		}
+		actualFileContentInBytes, _ := os.ReadFile(filePath)
+		assert.Equal(t, "services:\n    api:\n        environment:\n            - PORT=80\n            - DEBUG=1\n", string(actualFileContentInBytes))
+
+		current, err := Client{}.CurrentItems(filePath, items, WithArrayItemsStrategy(ArrayUnion))
+		assert.NoError(t, err)
+		assert.True(t, ItemsEqual(items, current), current)
+		os.Remove(filePath)
+	})
+}
+
+func TestArrayIndexFileTransform(t *testing.T) {
+	t.Run("Tweak the first element of an array without restating it", func(t *testing.T) {
+		filePath := "./test_artifact/array-index.json"
+		os.WriteFile(filePath, []byte(`{"servers":[{"host":"a","port":80},{"host":"b","port":80}]}`), 0666)
+		items := `{"servers":[{"port":8080}]}`
+
+		err := Client{}.FileTransform(filePath, items, filePath, WithArrayItemsStrategy(ArrayIndex))
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ := os.ReadFile(filePath)
+		assert.Equal(t, `{"servers":[{"host":"a","port":8080},{"host":"b","port":80}]}`, string(actualFileContentInBytes))
+
+		current, err := Client{}.CurrentItems(filePath, items, WithArrayItemsStrategy(ArrayIndex))
+		assert.NoError(t, err)
+		assert.True(t, ItemsEqual(items, current), current)
+		os.Remove(filePath)
+	})
+}
+
+func TestMergeRulesFileTransform(t *testing.T) {
+	t.Run("Append environment but replace command in compose file", func(t *testing.T) {
+		filePath := "./test_artifact/compose-rules.yml"
+		os.WriteFile(filePath, []byte("services:\n    api:\n        image: api:v1\n        command: [\"run\"]\n        environment:\n            - PORT=80\n"), 0666)
+		items := `{"services":{"api":{"image":"api:v2","command":["serve","--debug"],"environment":["DEBUG=1"]}}}`
+		options := []func(*Transformer){WithOverrideArrayItems(true), WithMergeRules([]MergeRule{
+			{Path: "services.*.environment", Strategy: ArrayUnion},
+			{Path: "services.*.image", Strategy: RuleKeepExisting},
+		})}
+
+		for i := 0; i < 2; i++ {
+			err := Client{}.FileTransform(filePath, items, filePath, options...)
+			assert.NoError(t, err)
+		}
+		actualFileContentInBytes, _ := os.ReadFile(filePath)
+		assert.Equal(t, "services:\n    api:\n        image: api:v1\n        command: [\"serve\", --debug]\n        environment:\n            - PORT=80\n            - DEBUG=1\n", string(actualFileContentInBytes))
+
+		current, err := Client{}.CurrentItems(filePath, items, options...)
+		assert.NoError(t, err)
+		assert.True(t, ItemsEqual(items, current), current)
+		os.Remove(filePath)
+	})
+}
+
+func TestUpdateFileTransform(t *testing.T) {
+	t.Run("Update a key without appending the same array elements again", func(t *testing.T) {
+		filePath := "./test_artifact/update-append.json"
+		os.WriteFile(filePath, []byte(`{"env":["A=1"],"name":"api"}`), 0666)
+
+		changes, err := Client{}.FileTransformChanges(filePath, `{"env":["B=2"],"version":"1"}`, filePath, WithOverrideArrayItems(false))
+		assert.NoError(t, err)
+		changes, err = Client{}.FileTransformChanges(filePath, `{"env":["B=2"],"version":"2"}`, filePath,
+			WithArrayItemsStrategy(ArrayAppend), WithPreviousChanges(changes))
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ := os.ReadFile(filePath)
+		assert.Equal(t, `{"env":["A=1","B=2"],"name":"api","version":"2"}`, string(actualFileContentInBytes))
+
+		err = Client{}.Revert(filePath, changes)
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ = os.ReadFile(filePath)
+		assert.Equal(t, `{"env":["A=1"],"name":"api"}`, string(actualFileContentInBytes))
+		os.Remove(filePath)
+	})
+}
+
+func TestTomlNumbers(t *testing.T) {
+	t.Run("Keep the floats of the file & write the integers of items as integers", func(t *testing.T) {
+		filePath := "./test_artifact/numbers.toml"
+		os.WriteFile(filePath, []byte("ratio = 1.0\nscale = 2.0\nretries = 3\n"), 0666)
+
+		err := Client{}.FileTransform(filePath, `{"port":8080,"scale":4,"weights":[1,2.5]}`, filePath)
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ := os.ReadFile(filePath)
+		assert.Equal(t, "port = 8080\nratio = 1.0\nretries = 3\nscale = 4.0\nweights = [1, 2.5]\n", string(actualFileContentInBytes))
+		os.Remove(filePath)
+	})
+	t.Run("Restore the integers & datetimes of the file when changes are reverted", func(t *testing.T) {
+		filePath := "./test_artifact/revert.toml"
+		original := "created = 1979-05-27T07:32:00Z\nday = 1979-05-27\nport = 80\n"
+		os.WriteFile(filePath, []byte(original), 0666)
+		// changes are saved as json in the state, so they are decoded from json as well
+		stateChanges := func(changes []Change) []Change {
+			b, _ := json.Marshal(changes)
+			var decoded []Change
+			json.Unmarshal(b, &decoded)
+			return decoded
+		}
+
+		changes, err := Client{}.FileTransformChanges(filePath, `{"port":8080,"created":"2023-01-02T15:04:05Z","day":"2023-01-02"}`, filePath)
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ := os.ReadFile(filePath)
+		assert.Equal(t, "created = 2023-01-02T15:04:05Z\nday = 2023-01-02\nport = 8080\n", string(actualFileContentInBytes))
+
+		changes, err = Client{}.FileTransformChanges(filePath, `{"port":9090}`, filePath, WithPreviousChanges(stateChanges(changes)))
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ = os.ReadFile(filePath)
+		assert.Equal(t, "created = 1979-05-27T07:32:00Z\nday = 1979-05-27\nport = 9090\n", string(actualFileContentInBytes))
+
+		err = Client{}.Revert(filePath, stateChanges(changes))
+		assert.NoError(t, err)
+		actualFileContentInBytes, _ = os.ReadFile(filePath)
+		assert.Equal(t, original, string(actualFileContentInBytes))
+		os.Remove(filePath)
+	})
+}
@@ -0,0 +1,416 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// CurrentItems reads the file and returns the value that each key defined in items currently has in it,
+// encoded with the same syntax as items. Keys that are not part of items are ignored, so the result is
+// equal to items unless the managed keys were changed (or removed) outside of terraform
+func (cl Client) CurrentItems(path, content string, options ...func(*Transformer)) (string, error) {
+	t, err := cl.newTransformer(path, content, "", options)
+	if err != nil {
+		return "", err
+	}
+	b, err := os.ReadFile(t.path)
+	if err != nil {
+		return "", err
+	}
+
+	if isDotEnv(t.path) {
+		fileContent, err := godotenv.Unmarshal(string(b))
+		if err != nil {
+			return "", err
+		}
+		if isJSONObject(t.items) {
+			return currentFlattenedItems(t.items, fileContent, t.keySeparator, t.keyPrefix, t.mergeStrategy == MergePatchStrategy)
+		}
+		envMap, err := godotenv.Unmarshal(t.items)
+		if err != nil {
+			return "", err
+		}
+		current := map[string]string{}
+		for k := range envMap {
+			if v, ok := fileContent[k]; ok {
+				current[k] = v
+			}
+		}
+		return godotenv.Marshal(current)
+	}
+
+	if isProperties(t.path) {
+		fileContent := decodeProperties(b)
+		if isJSONObject(t.items) {
+			return currentFlattenedItems(t.items, fileContent, ".", "", t.mergeStrategy == MergePatchStrategy)
+		}
+		current := map[string]string{}
+		for k := range decodeProperties([]byte(t.items)) {
+			if v, ok := fileContent[k]; ok {
+				current[k] = v
+			}
+		}
+		return encodeProperties(current), nil
+	}
+
+	fileContent, err := decodeFile(b, t.path)
+	if err != nil {
+		return "", err
+	}
+	srcContent, err := nestedItems(t)
+	if err != nil {
+		return "", err
+	}
+	if isIni(t.path) {
+		alignIniArrays(srcContent, fileContent)
+	}
+	if isXml(t.path) {
+		alignXmlArrays(srcContent, fileContent)
+	}
+	if isIni(t.path) || isXml(t.path) {
+		fileContent = stringTypedValues(srcContent, fileContent).(map[string]interface{})
+	}
+	current := currentValues(srcContent, fileContent, t, nil)
+	if t.mergeStrategy == MergePatchStrategy {
+		currentRemovedKeys(srcContent, fileContent, current)
+	}
+	if !isJSONObject(t.items) {
+		// items written using .env syntax are returned with the same syntax
+		return godotenv.Marshal(flattenEnv(current, t))
+	}
+	currentB, err := json.Marshal(current)
+	if err != nil {
+		return "", err
+	}
+	return string(currentB), nil
+}
+
+// currentFlattenedItems returns the value that each key defined in items (a JSON object) has in a file
+// holding flat keys, nested keys of items are joined with the separator and prefixed with the prefix. When
+// items are a merge patch, null values are kept as long as the keys they remove are absent from the file
+func currentFlattenedItems(items string, fileContent map[string]string, separator, prefix string, mergePatch bool) (string, error) {
+	srcContent := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(items), &srcContent); err != nil {
+		return "", err
+	}
+	current := map[string]interface{}{}
+	for k, v := range srcContent {
+		if c, ok := currentFlattenedValues(v, prefix+k, separator, fileContent, mergePatch); ok {
+			current[k] = c
+		}
+	}
+	currentB, err := json.Marshal(current)
+	if err != nil {
+		return "", err
+	}
+	return string(currentB), nil
+}
+
+// currentFlattenedValues replaces the leaves of items by the value of the key with the same (flattened)
+// name, leaves whose key has the same value are kept as they are in items
+func currentFlattenedValues(src interface{}, key, separator string, fileContent map[string]string, mergePatch bool) (interface{}, bool) {
+	switch value := src.(type) {
+	case map[string]interface{}:
+		current := map[string]interface{}{}
+		for k, v := range value {
+			if c, ok := currentFlattenedValues(v, key+separator+k, separator, fileContent, mergePatch); ok {
+				current[k] = c
+			}
+		}
+		return current, true
+	case []interface{}:
+		var current []interface{}
+		for i, v := range value {
+			if c, ok := currentFlattenedValues(v, fmt.Sprintf("%s[%d]", key, i), separator, fileContent, mergePatch); ok {
+				current = append(current, c)
+			}
+		}
+		return current, true
+	}
+	fileValue, ok := fileContent[key]
+	if src == nil && mergePatch {
+		// the key is removed by items, so it's reported as drift when the file still holds it
+		if ok {
+			return fileValue, true
+		}
+		for k := range fileContent {
+			if isNestedFlattenedKey(k, key, separator) {
+				return "", true
+			}
+		}
+		return nil, true
+	}
+	if !ok {
+		return nil, false
+	}
+	srcValue := map[string]string{}
+	flattenKeys(src, key, separator, srcValue)
+	if srcValue[key] == fileValue {
+		return src, true
+	}
+	return fileValue, true
+}
+
+// currentRemovedKeys sets the keys that items remove (the null values of a merge patch) to null in current
+// when they are absent from the file, so that removed keys are not reported as drift
+func currentRemovedKeys(src, dst, current map[string]interface{}) {
+	for k, srcValue := range src {
+		dstValue, ok := dst[k]
+		if srcValue == nil && !ok {
+			current[k] = nil
+			continue
+		}
+		srcMap, srcIsMap := srcValue.(map[string]interface{})
+		dstMap, dstIsMap := dstValue.(map[string]interface{})
+		currentMap, currentIsMap := current[k].(map[string]interface{})
+		if srcIsMap && dstIsMap && currentIsMap {
+			currentRemovedKeys(srcMap, dstMap, currentMap)
+		}
+	}
+}
+
+// currentValues walks the keys of src and collects the value they have in dst. When arrays are joined
+// (they are not replaced) the array found in dst is expected to contain the items of src, in that case
+// the array of src is returned since the elements owned by other tools must not be reported as drift.
+// Arrays merged by index or by a key field are compared element by element, path holds the keys of src
+func currentValues(src, dst map[string]interface{}, t Transformer, path []string) map[string]interface{} {
+	m := &Mergito{OverrideArray: t.overrideArrayItems, ArrayStrategy: t.arrayStrategy, ArrayMergeKeys: t.arrayMergeKeys, Rules: t.mergeRules}
+	current := map[string]interface{}{}
+	for k, srcValue := range src {
+		dstValue, ok := dst[k]
+		if !ok {
+			continue
+		}
+		keyPath := append(append([]string{}, path...), k)
+		if rule, ok := m.ruleAt(keyPath); ok {
+			switch rule.Strategy {
+			// values kept by the rule belong to the file, so they are never reported as drift
+			case RuleKeepExisting:
+				current[k] = srcValue
+				continue
+			case RuleOverride:
+				current[k] = dstValue
+				continue
+			}
+		}
+		srcMap, srcIsMap := srcValue.(map[string]interface{})
+		dstMap, dstIsMap := dstValue.(map[string]interface{})
+		srcSlice, srcIsSlice := srcValue.([]interface{})
+		dstSlice, dstIsSlice := dstValue.([]interface{})
+		strategy, mergeKey, _ := m.arrayStrategyAt(keyPath)
+		switch {
+		case srcIsMap && dstIsMap:
+			current[k] = currentValues(srcMap, dstMap, t, keyPath)
+		case srcIsSlice && dstIsSlice && strategy == ArrayByKey:
+			current[k] = currentKeyedElements(srcSlice, dstSlice, mergeKey, t, keyPath)
+		case srcIsSlice && dstIsSlice && strategy == ArrayIndex:
+			current[k] = currentIndexedElements(srcSlice, dstSlice, t, keyPath)
+		case srcIsSlice && dstIsSlice && strategy != ArrayReplace && containsAll(dstSlice, srcSlice):
+			current[k] = srcValue
+		default:
+			current[k] = dstValue
+		}
+	}
+	return current
+}
+
+// stringTypedValues converts the values read from files holding strings (ini and xml) to the type of the
+// value placed in the same path of src when both are written the same way, so `RestartSec=5` (read as
+// "5") is equal to the number 5 of items. The elements of arrays are converted like the element of src they
+// are equal to, or like the element placed in the same position otherwise
+func stringTypedValues(src, dst interface{}) interface{} {
+	switch value := dst.(type) {
+	case map[string]interface{}:
+		srcMap, _ := src.(map[string]interface{})
+		c := make(map[string]interface{}, len(value))
+		for k, e := range value {
+			c[k] = stringTypedValues(srcMap[k], e)
+		}
+		return c
+	case []interface{}:
+		srcSlice, isSlice := src.([]interface{})
+		c := make([]interface{}, len(value))
+		for i, e := range value {
+			c[i] = e
+			if !isSlice {
+				continue
+			}
+			if i < len(srcSlice) {
+				c[i] = stringTypedValues(srcSlice[i], e)
+			}
+			for _, srcElement := range srcSlice {
+				if converted := stringTypedValues(srcElement, e); jsonEqual(converted, srcElement) {
+					c[i] = converted
+					break
+				}
+			}
+		}
+		return c
+	case string:
+		switch src.(type) {
+		case float64, bool:
+			if stringValue(src) == value {
+				return src
+			}
+		}
+	}
+	return dst
+}
+
+// currentIndexedElements returns the current value of the elements of src, which are compared with the
+// element of dst placed in the same position. Elements beyond the length of dst are left out
+func currentIndexedElements(src, dst []interface{}, t Transformer, path []string) []interface{} {
+	current := []interface{}{}
+	for i := 0; i < len(src) && i < len(dst); i++ {
+		srcMap, srcIsMap := src[i].(map[string]interface{})
+		dstMap, dstIsMap := dst[i].(map[string]interface{})
+		srcSlice, srcIsSlice := src[i].([]interface{})
+		dstSlice, dstIsSlice := dst[i].([]interface{})
+		switch {
+		case srcIsMap && dstIsMap:
+			current = append(current, currentValues(srcMap, dstMap, t, path))
+		case srcIsSlice && dstIsSlice:
+			current = append(current, currentIndexedElements(srcSlice, dstSlice, t, path))
+		default:
+			current = append(current, dst[i])
+		}
+	}
+	return current
+}
+
+// currentKeyedElements returns the current value of the elements of src, objects are looked up in dst by
+// the value of their key field and elements that can't be found are left out
+func currentKeyedElements(src, dst []interface{}, key string, t Transformer, path []string) []interface{} {
+	current := []interface{}{}
+	for _, e := range src {
+		if i := indexByKey(dst, e, key); i >= 0 {
+			current = append(current, currentValues(e.(map[string]interface{}), dst[i].(map[string]interface{}), t, path))
+			continue
+		}
+		if containsAll(dst, []interface{}{e}) {
+			current = append(current, e)
+		}
+	}
+	return current
+}
+
+func containsAll(values, items []interface{}) bool {
+	for _, item := range items {
+		found := false
+		for _, v := range values {
+			if jsonEqual(v, item) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonEqual compares two values as they would be encoded in JSON, so numbers decoded
+// as int (yaml) and float64 (json) holding the same value are equal
+func jsonEqual(a, b interface{}) bool {
+	var aNormalized, bNormalized interface{}
+	aB, errA := json.Marshal(a)
+	bB, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	json.Unmarshal(aB, &aNormalized)
+	json.Unmarshal(bB, &bNormalized)
+	return reflect.DeepEqual(aNormalized, bNormalized)
+}
+
+// ItemsEqual reports whether two items values hold the same content, either as JSON documents
+// or as .env variables, regardless of formatting differences
+func ItemsEqual(a, b string) bool {
+	var aJson, bJson interface{}
+	if json.Unmarshal([]byte(a), &aJson) == nil && json.Unmarshal([]byte(b), &bJson) == nil {
+		return reflect.DeepEqual(aJson, bJson)
+	}
+	aEnv, errA := godotenv.Unmarshal(a)
+	bEnv, errB := godotenv.Unmarshal(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return reflect.DeepEqual(aEnv, bEnv)
+}
+
+// ImportItems reads the file and returns its content encoded with the syntax expected by items. When a key
+// path is provided only the value found in that path is returned, nested in the keys of the path
+func (cl Client) ImportItems(path string, keyPath []string) (string, error) {
+	path, err := cl.ResolvePath(path)
+	if err != nil {
+		return "", err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	if isDotEnv(path) {
+		fileContent, err := godotenv.Unmarshal(string(b))
+		if err != nil {
+			return "", err
+		}
+		if len(keyPath) == 0 {
+			return godotenv.Marshal(fileContent)
+		}
+		v, ok := fileContent[keyPath[0]]
+		if !ok || len(keyPath) > 1 {
+			return "", fmt.Errorf("Key %s does not exist in file %s", strings.Join(keyPath, "."), path)
+		}
+		return godotenv.Marshal(map[string]string{keyPath[0]: v})
+	}
+
+	if isProperties(path) {
+		fileContent := decodeProperties(b)
+		if len(keyPath) == 0 {
+			return encodeProperties(fileContent), nil
+		}
+		// the key path selects the property with the same name and the properties nested in it
+		prefix := strings.Join(keyPath, ".")
+		selected := map[string]string{}
+		for k, v := range fileContent {
+			if k == prefix || strings.HasPrefix(k, prefix+".") || strings.HasPrefix(k, prefix+"[") {
+				selected[k] = v
+			}
+		}
+		if len(selected) == 0 {
+			return "", fmt.Errorf("Key %s does not exist in file %s", prefix, path)
+		}
+		return encodeProperties(selected), nil
+	}
+
+	fileContent, err := decodeFile(b, path)
+	if err != nil {
+		return "", err
+	}
+	var selected interface{} = fileContent
+	for _, k := range keyPath {
+		m, ok := selected.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("Key %s does not exist in file %s", strings.Join(keyPath, "."), path)
+		}
+		if selected, ok = m[k]; !ok {
+			return "", fmt.Errorf("Key %s does not exist in file %s", strings.Join(keyPath, "."), path)
+		}
+	}
+	// the selected value is nested back in the keys of the path, so it's merged in the same place
+	for i := len(keyPath) - 1; i >= 0; i-- {
+		selected = map[string]interface{}{keyPath[i]: selected}
+	}
+	itemsB, err := json.Marshal(selected)
+	if err != nil {
+		return "", err
+	}
+	return string(itemsB), nil
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestItemsEqual(t *testing.T) {
+	t.Run("Compare items regardless of formatting", func(t *testing.T) {
+		assert.True(t, ItemsEqual(`{"a":1,"b":[1,2]}`, "{\n  \"b\": [1, 2],\n  \"a\": 1\n}"))
+		assert.False(t, ItemsEqual(`{"a":1}`, `{"a":2}`))
+		assert.True(t, ItemsEqual("A=1\nB=2", "\tB=2\n\tA=1\n"))
+		assert.False(t, ItemsEqual("A=1", "A=2"))
+	})
+}
+
+func TestCurrentValues(t *testing.T) {
+	t.Run("Array joined with elements owned by other tools is not reported as drift", func(t *testing.T) {
+		src := map[string]interface{}{"Teams": []interface{}{"Inter"}}
+		dst := map[string]interface{}{"Teams": []interface{}{"Roma", "Inter"}}
+		assert.Equal(t, src, currentValues(src, dst, Transformer{overrideArrayItems: false}, nil))
+		assert.Equal(t, dst, currentValues(src, dst, Transformer{overrideArrayItems: true}, nil))
+	})
+}
+
+func TestCurrentRemovedKeys(t *testing.T) {
+	t.Run("Keys removed by a merge patch are not reported as drift", func(t *testing.T) {
+		src := map[string]interface{}{"a": nil, "b": map[string]interface{}{"c": nil}, "d": nil}
+		dst := map[string]interface{}{"b": map[string]interface{}{}, "d": "e"}
+		current := currentValues(src, dst, Transformer{overrideArrayItems: true}, nil)
+		currentRemovedKeys(src, dst, current)
+		assert.Equal(t, map[string]interface{}{"a": nil, "b": map[string]interface{}{"c": nil}, "d": "e"}, current)
+	})
+}
+
+func TestCurrentKeyedElements(t *testing.T) {
+	t.Run("Elements merged by key are compared with the element of the file with the same key", func(t *testing.T) {
+		src := map[string]interface{}{"containers": []interface{}{
+			map[string]interface{}{"name": "api", "image": "api:v2"},
+			map[string]interface{}{"name": "sidecar"},
+		}}
+		dst := map[string]interface{}{"containers": []interface{}{
+			map[string]interface{}{"name": "db", "image": "postgres"},
+			map[string]interface{}{"name": "api", "image": "api:v1", "ports": []interface{}{80.0}},
+		}}
+		transformer := Transformer{arrayMergeKeys: map[string]string{"containers": "name"}}
+		expected := map[string]interface{}{"containers": []interface{}{
+			map[string]interface{}{"name": "api", "image": "api:v1"},
+		}}
+		assert.Equal(t, expected, currentValues(src, dst, transformer, nil))
+	})
+}
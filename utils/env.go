@@ -0,0 +1,290 @@
+package utils
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// .env files are decoded with godotenv, envPatch writes them back line by line so comments (inline comments
+// included), blank lines, `export` prefixes, the order of the variables and the quoting style of the values are kept
+
+type envLine struct {
+	raw string
+	// key is empty for comments, blank lines and lines that can't be parsed
+	key    string
+	prefix string
+	quote  string
+	// comment holds the inline comment following the value, with the spaces before it
+	comment string
+}
+
+// defaultKeySeparator joins the nested keys of json/yaml content when it's written to .env files
+const defaultKeySeparator = "__"
+
+// WithKeySeparator sets the separator used to join nested keys when content is converted to .env
+// variables, and to split the variables when .env files are converted to nested content
+func WithKeySeparator(separator string) func(*Transformer) {
+	return func(m *Transformer) {
+		m.keySeparator = separator
+	}
+}
+
+// WithKeyPrefix sets the prefix added to the variables written to .env files, when .env files are
+// converted to nested content only the variables with the prefix are taken into account
+func WithKeyPrefix(prefix string) func(*Transformer) {
+	return func(m *Transformer) {
+		m.keyPrefix = prefix
+	}
+}
+
+var (
+	envUnquotedValue = regexp.MustCompile(`^[A-Za-z0-9_./:@,+%=-]*$`)
+	envInlineComment = regexp.MustCompile(`\s+#.*$`)
+)
+
+func parseEnv(in []byte) []envLine {
+	var lines []envLine
+	natural := strings.Split(strings.ReplaceAll(string(in), "\r\n", "\n"), "\n")
+	if len(natural) > 0 && natural[len(natural)-1] == "" {
+		natural = natural[:len(natural)-1]
+	}
+	for _, raw := range natural {
+		text := strings.TrimSpace(raw)
+		if text == "" || strings.HasPrefix(text, "#") {
+			lines = append(lines, envLine{raw: raw})
+			continue
+		}
+		values, err := godotenv.Unmarshal(text)
+		separator := strings.IndexAny(raw, "=:")
+		if err != nil || len(values) != 1 || separator < 0 {
+			lines = append(lines, envLine{raw: raw})
+			continue
+		}
+		line := envLine{raw: raw}
+		for k := range values {
+			line.key = k
+		}
+		// the prefix holds everything before the value (indentation, `export` and the separator)
+		value := strings.TrimLeft(raw[separator+1:], " ")
+		line.prefix = raw[:len(raw)-len(value)]
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			line.quote = value[:1]
+		}
+		line.comment = envComment(value, line.quote)
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// envPatch writes the variables reusing the lines of the original file, the lines of the variables whose
+// value did not change are kept as they are, removed variables are dropped and new variables are appended
+// at the end of the file
+func envPatch(original []byte, content map[string]string) ([]byte, error) {
+	originalContent, err := godotenv.Unmarshal(string(original))
+	if err != nil {
+		return nil, err
+	}
+	lines := parseEnv(original)
+	// when a variable is defined more than once, the last definition is the one holding its value
+	last := map[string]int{}
+	for i, l := range lines {
+		if l.key != "" {
+			last[l.key] = i
+		}
+	}
+
+	var out []string
+	for i, l := range lines {
+		if l.key == "" {
+			out = append(out, l.raw)
+			continue
+		}
+		v, ok := content[l.key]
+		if !ok {
+			continue
+		}
+		if last[l.key] != i || originalContent[l.key] == v {
+			out = append(out, l.raw)
+			continue
+		}
+		out = append(out, l.prefix+envQuote(v, l.quote)+l.comment)
+	}
+
+	newContent := map[string]string{}
+	for k, v := range content {
+		if _, ok := last[k]; !ok {
+			newContent[k] = v
+		}
+	}
+	if len(newContent) > 0 {
+		newLines, err := godotenv.Marshal(newContent)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, newLines)
+	}
+	if len(out) == 0 {
+		return []byte{}, nil
+	}
+	return []byte(strings.Join(out, "\n") + "\n"), nil
+}
+
+// envComment returns the inline comment following the value, that is a `#` preceded by spaces placed after
+// the closing quote of quoted values
+func envComment(value, quote string) string {
+	end := 0
+	if quote != "" {
+		end = -1
+		for i := 1; i < len(value); i++ {
+			if quote == `"` && value[i] == '\\' {
+				i++
+				continue
+			}
+			if value[i:i+1] == quote {
+				end = i + 1
+				break
+			}
+		}
+		if end < 0 {
+			return ""
+		}
+	}
+	if loc := envInlineComment.FindStringIndex(value[end:]); loc != nil {
+		return value[end+loc[0]:]
+	}
+	return ""
+}
+
+// envQuote writes the value using the given quoting style, values that can't be written with that style
+// are double quoted
+func envQuote(value, quote string) string {
+	switch {
+	case quote == "'" && !strings.ContainsAny(value, "'\n\r"):
+		return "'" + value + "'"
+	case quote == "" && envUnquotedValue.MatchString(value):
+		return value
+	}
+	return `"` + envDoubleQuoteEscape(value) + `"`
+}
+
+// envDoubleQuoteEscape escapes the same characters as godotenv.Marshal
+func envDoubleQuoteEscape(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`, `"`, `\"`, `!`, `\!`, `$`, `\$`, "`", "\\`")
+	return replacer.Replace(value)
+}
+
+// flattenEnv converts nested content to .env variables, nested keys are joined with the key separator
+func flattenEnv(content map[string]interface{}, t Transformer) map[string]string {
+	flattened := map[string]string{}
+	flattenKeys(content, "", t.keySeparator, flattened)
+	values := make(map[string]string, len(flattened))
+	for k, v := range flattened {
+		values[t.keyPrefix+k] = v
+	}
+	return values
+}
+
+// unflattenEnv converts .env variables to nested content, variables are split by the key separator
+func unflattenEnv(values map[string]string, t Transformer) map[string]interface{} {
+	content := map[string]interface{}{}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	// sorted keys make the result deterministic when a variable is also the parent of other variables
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !strings.HasPrefix(k, t.keyPrefix) || k == t.keyPrefix {
+			continue
+		}
+		path := []string{strings.TrimPrefix(k, t.keyPrefix)}
+		if t.keySeparator != "" {
+			path = strings.Split(path[0], t.keySeparator)
+		}
+		parent, _ := lookupMap(content, path[:len(path)-1], true)
+		if _, isMap := parent[path[len(path)-1]].(map[string]interface{}); !isMap {
+			parent[path[len(path)-1]] = values[k]
+		}
+	}
+	return content
+}
+
+// envContent decodes the file as .env variables, files with other formats are decoded and flattened
+func envContent(b []byte, path string, t Transformer) (map[string]string, error) {
+	if isDotEnv(path) {
+		return godotenv.Unmarshal(string(b))
+	}
+	content, err := decodeFile(b, path)
+	if err != nil {
+		return nil, err
+	}
+	return flattenEnv(content, t), nil
+}
+
+// envItems decodes items written either using .env syntax or as a JSON object, which is flattened
+func envItems(t Transformer) (map[string]string, error) {
+	if !isJSONObject(t.items) {
+		return godotenv.Unmarshal(t.items)
+	}
+	content := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(t.items), &content); err != nil {
+		return nil, err
+	}
+	return flattenEnv(content, t), nil
+}
+
+// nestedItems decodes items written either as a JSON object or using .env syntax, which is unflattened
+func nestedItems(t Transformer) (map[string]interface{}, error) {
+	content := map[string]interface{}{}
+	if isJSONObject(t.items) {
+		err := json.Unmarshal([]byte(t.items), &content)
+		return content, err
+	}
+	values, err := godotenv.Unmarshal(t.items)
+	if err != nil {
+		return nil, err
+	}
+	return unflattenEnv(values, t), nil
+}
+
+func isJSONObject(items string) bool {
+	return strings.HasPrefix(strings.TrimSpace(items), "{")
+}
+
+// removedKeys returns the flattened keys that items removes from the file, that is the keys whose value
+// is null when items (a JSON object) are applied as a merge patch
+func (t Transformer) removedKeys(separator, prefix string) ([]string, error) {
+	if t.mergeStrategy != MergePatchStrategy || !isJSONObject(t.items) {
+		return nil, nil
+	}
+	content := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(t.items), &content); err != nil {
+		return nil, err
+	}
+	var keys []string
+	nullKeys(content, "", separator, &keys)
+	for i, k := range keys {
+		keys[i] = prefix + k
+	}
+	return keys, nil
+}
+
+// deleteFlattenedKeys removes the keys from the content, as well as the keys nested in them
+func deleteFlattenedKeys(content map[string]interface{}, keys []string, separator string) {
+	for _, key := range keys {
+		for k := range content {
+			if k == key || isNestedFlattenedKey(k, key, separator) {
+				delete(content, k)
+			}
+		}
+	}
+}
+
+// isNestedFlattenedKey reports whether the flattened key k is nested in key
+func isNestedFlattenedKey(k, key, separator string) bool {
+	return strings.HasPrefix(k, key+separator) || strings.HasPrefix(k, key+"[")
+}
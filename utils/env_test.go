@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvPatch(t *testing.T) {
+	t.Run("Keep comments, export prefixes, order & quoting", func(t *testing.T) {
+		testContent := []struct {
+			original string
+			content  map[string]string
+			expected string
+		}{
+			{
+				original: "# database\nexport DB_HOST=localhost\nDB_USER='app'\n\nDB_PASS=\"s3cr3t\"\nLEGACY=1\n",
+				content: map[string]string{
+					"DB_HOST": "db.internal",
+					"DB_USER": "admin",
+					"DB_PASS": "s3cr3t",
+					"DB_NAME": "app db",
+				},
+				expected: "# database\nexport DB_HOST=db.internal\nDB_USER='admin'\n\nDB_PASS=\"s3cr3t\"\nDB_NAME=\"app db\"\n",
+			},
+			{
+				original: "GREETING=hello\nNAME='x'\n",
+				content:  map[string]string{"GREETING": "hello world", "NAME": "it's me"},
+				expected: "GREETING=\"hello world\"\nNAME=\"it's me\"\n",
+			},
+			{
+				original: "A=x # tail\nB='y' # quoted\nC=\"a \\\" # b\"   # escaped\nD=e#f\n",
+				content:  map[string]string{"A": "z", "B": "w", "C": "c", "D": "g"},
+				expected: "A=z # tail\nB='w' # quoted\nC=\"c\"   # escaped\nD=g\n",
+			},
+			{
+				original: "PORT=80\nPORT=8080\n",
+				content:  map[string]string{"PORT": "9090"},
+				expected: "PORT=80\nPORT=9090\n",
+			},
+		}
+		for _, value := range testContent {
+			out, err := envPatch([]byte(value.original), value.content)
+			assert.NoError(t, err)
+			assert.Equal(t, value.expected, string(out))
+		}
+	})
+}
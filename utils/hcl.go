@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// HCL attribute files (terraform.tfvars, *.auto.tfvars) are mapped to the map model used by Merge as a
+// JSON object whose keys are the attributes of the file. Values must be literals (strings, numbers, bools,
+// lists and objects), expressions that reference variables or functions can't be decoded. Files with the
+// .tfvars.json extension are plain JSON files, so they are handled by the JSON codec.
+
+var supportedHclExt = []string{".tfvars", ".hcl"}
+
+func init() {
+	for _, ext := range supportedHclExt {
+		supportedFileExtDecode[ext] = hclUnmarshal
+		supportedFileExtEncode[ext] = hclMarshal
+		supportedFileExtPatch[ext] = hclPatch
+	}
+}
+
+func hclUnmarshal(in []byte, out interface{}) error {
+	content, ok := out.(*map[string]interface{})
+	if !ok {
+		return errors.New("HCL content can only be decoded to map[string]interface{}")
+	}
+	if *content == nil {
+		*content = map[string]interface{}{}
+	}
+	file, diags := hclsyntax.ParseConfig(in, "", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return diags
+	}
+	attributes, diags := file.Body.JustAttributes()
+	if diags.HasErrors() {
+		return diags
+	}
+	for name, attribute := range attributes {
+		value, diags := attribute.Expr.Value(nil)
+		if diags.HasErrors() {
+			return diags
+		}
+		v, err := ctyToInterface(value)
+		if err != nil {
+			return fmt.Errorf("Value of attribute %s can't be decoded: %s", name, err.Error())
+		}
+		(*content)[name] = v
+	}
+	return nil
+}
+
+func hclMarshal(in interface{}) ([]byte, error) {
+	return hclPatch(nil, in)
+}
+
+// hclPatch writes the content reusing the layout of the original file: comments, the order of the
+// attributes and the attributes whose value did not change are kept, removed attributes are dropped
+// and new attributes are appended at the end of the file
+func hclPatch(original []byte, in interface{}) ([]byte, error) {
+	content, ok := in.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("HCL content must be an object")
+	}
+	file, diags := hclwrite.ParseConfig(original, "", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	originalContent := map[string]interface{}{}
+	if err := hclUnmarshal(original, &originalContent); err != nil {
+		return nil, err
+	}
+
+	body := file.Body()
+	for name := range body.Attributes() {
+		if _, ok := content[name]; !ok {
+			body.RemoveAttribute(name)
+		}
+	}
+	for _, name := range sortedKeys(content) {
+		if originalValue, ok := originalContent[name]; ok && jsonEqual(originalValue, content[name]) {
+			continue
+		}
+		value, err := interfaceToCty(content[name])
+		if err != nil {
+			return nil, fmt.Errorf("Value of attribute %s can't be written in HCL files: %s", name, err.Error())
+		}
+		body.SetAttributeValue(name, value)
+	}
+	return hclwrite.Format(file.Bytes()), nil
+}
+
+// ctyToInterface converts the value to the types produced by the JSON decoder
+func ctyToInterface(value cty.Value) (interface{}, error) {
+	b, err := ctyjson.Marshal(value, cty.DynamicPseudoType)
+	if err != nil {
+		return nil, err
+	}
+	// values marshaled with the dynamic type are wrapped in an object holding the value and its type
+	var wrapped struct {
+		Value interface{} `json:"value"`
+	}
+	if err := json.Unmarshal(b, &wrapped); err != nil {
+		return nil, err
+	}
+	return wrapped.Value, nil
+}
+
+func interfaceToCty(v interface{}) (cty.Value, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	t, err := ctyjson.ImpliedType(b)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return ctyjson.Unmarshal(b, t)
+}
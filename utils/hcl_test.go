@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHclUnmarshal(t *testing.T) {
+	t.Run("Map attributes to a JSON object", func(t *testing.T) {
+		in := `# network
+region        = "eu-west-1"
+instance_count = 3
+enabled       = true
+zones         = ["a", "b"]
+tags = {
+  team = "platform"
+}
+`
+		content := map[string]interface{}{}
+		err := hclUnmarshal([]byte(in), &content)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"region":         "eu-west-1",
+			"instance_count": float64(3),
+			"enabled":        true,
+			"zones":          []interface{}{"a", "b"},
+			"tags":           map[string]interface{}{"team": "platform"},
+		}, content)
+	})
+	t.Run("Return error when values are not literals", func(t *testing.T) {
+		content := map[string]interface{}{}
+		err := hclUnmarshal([]byte("region = var.region\n"), &content)
+		assert.Error(t, err)
+	})
+}
+
+func TestHclPatch(t *testing.T) {
+	t.Run("Keep comments, order & untouched attributes", func(t *testing.T) {
+		original := "# network\nregion = \"eu-west-1\"\n\n# sizing\ninstance_count = 3\nlegacy = true\n"
+		content := map[string]interface{}{
+			"region":         "eu-west-1",
+			"instance_count": float64(5),
+			"tags":           map[string]interface{}{"team": "platform"},
+		}
+		out, err := hclPatch([]byte(original), content)
+		assert.NoError(t, err)
+		assert.Equal(t, "# network\nregion = \"eu-west-1\"\n\n# sizing\ninstance_count = 5\ntags = {\n  team = \"platform\"\n}\n", string(out))
+	})
+}
@@ -0,0 +1,338 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// INI files (as well as .gitconfig and systemd unit files) are mapped to the map model used by Merge
+// in the following way:
+//   - keys defined before the first section are placed in the root of the map
+//   - every section is a nested map whose keys are the keys of the section, sections with the same
+//     name are merged into the same map
+//   - keys that are repeated in the same section (e.g. systemd `ExecStartPre=`) are decoded as an array
+//     holding all values in the order they are defined
+//
+// Values are kept as strings (quotes included), comments and blank lines are ignored by the decoder but
+// kept by iniPatch when an existing file is rewritten.
+
+var supportedIniExt = []string{".ini", ".cfg", ".gitconfig", ".service", ".socket", ".timer", ".mount", ".target", ".path"}
+
+type iniLine struct {
+	// raw holds the line as it is in the file, continuation lines included
+	raw string
+	// key is empty for comments, blank lines and section headers
+	key       string
+	value     string
+	indent    string
+	separator string
+}
+
+type iniSection struct {
+	// name is empty for the keys defined before the first section
+	name   string
+	header string
+	lines  []iniLine
+}
+
+func init() {
+	for _, ext := range supportedIniExt {
+		supportedFileExtDecode[ext] = iniUnmarshal
+		supportedFileExtEncode[ext] = iniMarshal
+		supportedFileExtPatch[ext] = iniPatch
+	}
+}
+
+func isIni(path string) bool {
+	ext := filepath.Ext(path)
+	for _, e := range supportedIniExt {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+func parseIni(in []byte) []*iniSection {
+	sections := []*iniSection{{}}
+	current := sections[0]
+	lines := strings.Split(string(in), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for i := 0; i < len(lines); i++ {
+		raw := lines[i]
+		text := strings.TrimSpace(raw)
+		switch {
+		case text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, ";"):
+			current.lines = append(current.lines, iniLine{raw: raw})
+			continue
+		case strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]"):
+			current = &iniSection{name: strings.TrimSpace(text[1 : len(text)-1]), header: raw}
+			sections = append(sections, current)
+			continue
+		}
+		// a backslash at the end of the line continues the value in the next line
+		for strings.HasSuffix(text, "\\") && i+1 < len(lines) {
+			i++
+			raw += "\n" + lines[i]
+			text = strings.TrimSpace(strings.TrimSuffix(text, "\\")) + " " + strings.TrimSpace(lines[i])
+		}
+		line := iniLine{raw: raw, indent: raw[:len(raw)-len(strings.TrimLeft(raw, " \t"))]}
+		sep := strings.IndexAny(text, "=:")
+		if sep < 0 {
+			line.key = text
+		} else {
+			line.key = strings.TrimSpace(text[:sep])
+			line.value = strings.TrimSpace(text[sep+1:])
+			line.separator = text[len(strings.TrimRight(text[:sep], " \t")) : len(text)-len(strings.TrimLeft(text[sep+1:], " \t"))]
+		}
+		current.lines = append(current.lines, line)
+	}
+	return sections
+}
+
+// iniValues returns the values of the section, repeated keys are decoded as arrays
+func iniValues(lines []iniLine, values map[string]interface{}) {
+	for _, l := range lines {
+		if l.key == "" {
+			continue
+		}
+		switch v := values[l.key].(type) {
+		case nil:
+			values[l.key] = l.value
+		case string:
+			values[l.key] = []interface{}{v, l.value}
+		case []interface{}:
+			values[l.key] = append(v, l.value)
+		}
+	}
+}
+
+func iniUnmarshal(in []byte, out interface{}) error {
+	content, ok := out.(*map[string]interface{})
+	if !ok {
+		return errors.New("INI content can only be decoded to map[string]interface{}")
+	}
+	if *content == nil {
+		*content = map[string]interface{}{}
+	}
+	for _, s := range parseIni(in) {
+		if s.name == "" {
+			iniValues(s.lines, *content)
+			continue
+		}
+		values, ok := (*content)[s.name].(map[string]interface{})
+		if !ok {
+			values = map[string]interface{}{}
+			(*content)[s.name] = values
+		}
+		iniValues(s.lines, values)
+	}
+	return nil
+}
+
+func iniMarshal(in interface{}) ([]byte, error) {
+	return iniPatch(nil, in)
+}
+
+// iniPatch writes the content reusing the layout of the original file: comments, blank lines, the order
+// of sections and keys and the lines whose value did not change are kept, removed keys are dropped and new
+// keys are appended at the end of their section (new sections at the end of the file)
+func iniPatch(original []byte, in interface{}) ([]byte, error) {
+	content, ok := in.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("INI content must be an object")
+	}
+	global := map[string]interface{}{}
+	for k, v := range content {
+		if _, isMap := v.(map[string]interface{}); !isMap {
+			global[k] = v
+		}
+	}
+
+	sections := parseIni(original)
+	originalValues := map[string]map[string]interface{}{}
+	for _, s := range sections {
+		if originalValues[s.name] == nil {
+			originalValues[s.name] = map[string]interface{}{}
+		}
+		iniValues(s.lines, originalValues[s.name])
+	}
+
+	// new keys are written with the indentation and separator used by the first key of the file
+	indent, separator := "", "="
+style:
+	for _, s := range sections {
+		for _, l := range s.lines {
+			if l.key != "" && l.separator != "" {
+				indent, separator = l.indent, l.separator
+				break style
+			}
+		}
+	}
+
+	var out []string
+	written := map[string]bool{}
+	for _, s := range sections {
+		values := global
+		if s.name != "" {
+			sectionValues, ok := content[s.name].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			values = sectionValues
+			out = append(out, s.header)
+		}
+		lines, err := iniSectionLines(s, values, originalValues[s.name], written[s.name], indent, separator)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, lines...)
+		written[s.name] = true
+	}
+
+	for _, name := range sortedKeys(content) {
+		values, ok := content[name].(map[string]interface{})
+		if !ok || written[name] {
+			continue
+		}
+		if len(out) > 0 && strings.TrimSpace(out[len(out)-1]) != "" {
+			out = append(out, "")
+		}
+		out = append(out, "["+name+"]")
+		lines, err := iniSectionLines(&iniSection{name: name}, values, nil, false, indent, separator)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, lines...)
+	}
+	if len(out) == 0 {
+		return []byte{}, nil
+	}
+	return []byte(strings.Join(out, "\n") + "\n"), nil
+}
+
+func iniSectionLines(s *iniSection, values, originalValues map[string]interface{}, repeated bool, indent, separator string) ([]string, error) {
+	var out []string
+	emitted := map[string]bool{}
+	last := 0
+	for _, l := range s.lines {
+		if l.key == "" {
+			out = append(out, l.raw)
+			continue
+		}
+		v, ok := values[l.key]
+		if !ok {
+			continue
+		}
+		// unchanged keys keep their lines (and position) untouched
+		if reflect.DeepEqual(iniStrings(v), iniStrings(originalValues[l.key])) {
+			out = append(out, l.raw)
+			last = len(out)
+			continue
+		}
+		if emitted[l.key] {
+			continue
+		}
+		emitted[l.key] = true
+		lines, err := iniKeyLines(l.key, v, l.indent, l.separator)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, lines...)
+		last = len(out)
+	}
+	if repeated {
+		return out, nil
+	}
+
+	// new keys are placed after the last key of the section, so trailing comments and blank lines stay at the end
+	var newLines []string
+	for _, k := range sortedKeys(values) {
+		if _, ok := originalValues[k]; ok {
+			continue
+		}
+		lines, err := iniKeyLines(k, values[k], indent, separator)
+		if err != nil {
+			return nil, err
+		}
+		newLines = append(newLines, lines...)
+	}
+	if last == 0 {
+		for last < len(out) && strings.TrimSpace(out[last]) == "" {
+			last++
+		}
+	}
+	return append(out[:last], append(newLines, out[last:]...)...), nil
+}
+
+func iniKeyLines(key string, v interface{}, indent, separator string) ([]string, error) {
+	if separator == "" {
+		separator = "="
+	}
+	values := iniStrings(v)
+	if values == nil {
+		return nil, fmt.Errorf("Value of key %s can't be written in INI files, only one level of sections is supported", key)
+	}
+	lines := make([]string, 0, len(values))
+	for _, value := range values {
+		lines = append(lines, indent+key+separator+value)
+	}
+	return lines, nil
+}
+
+// iniStrings returns the string representation of the values of a key, nil is returned
+// when the value can't be represented in INI files (nested maps and arrays)
+func iniStrings(v interface{}) []string {
+	switch value := v.(type) {
+	case nil:
+		return []string{""}
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, e := range value {
+			s := iniStrings(e)
+			if len(s) != 1 {
+				return nil
+			}
+			if _, isSlice := e.([]interface{}); isSlice {
+				return nil
+			}
+			values = append(values, s[0])
+		}
+		return values
+	case map[string]interface{}:
+		return nil
+	}
+	return []string{stringValue(v)}
+}
+
+// alignIniArrays converts to arrays the values of the file that are defined once when the same key holds
+// an array in items, so that values can be joined (or overridden) the same way as repeated keys
+func alignIniArrays(src, dst map[string]interface{}) {
+	for k, srcValue := range src {
+		switch srcValue.(type) {
+		case []interface{}:
+			if s, ok := dst[k].(string); ok {
+				dst[k] = []interface{}{s}
+			}
+		case map[string]interface{}:
+			if dstMap, ok := dst[k].(map[string]interface{}); ok {
+				alignIniArrays(srcValue.(map[string]interface{}), dstMap)
+			}
+		}
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
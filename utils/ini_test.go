@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIniUnmarshal(t *testing.T) {
+	t.Run("Map sections to nested maps & repeated keys to arrays", func(t *testing.T) {
+		in := `; global settings
+root = true
+
+[Unit]
+Description=My service
+
+[Service]
+ExecStartPre=/bin/mkdir -p /var/lib/app
+ExecStartPre=/bin/chown app /var/lib/app
+ExecStart=/usr/bin/app \
+  --verbose
+Restart=on-failure
+
+[remote "origin"]
+	url = https://github.com/foo/bar.git
+`
+		content := map[string]interface{}{}
+		err := iniUnmarshal([]byte(in), &content)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"root": "true",
+			"Unit": map[string]interface{}{"Description": "My service"},
+			"Service": map[string]interface{}{
+				"ExecStartPre": []interface{}{"/bin/mkdir -p /var/lib/app", "/bin/chown app /var/lib/app"},
+				"ExecStart":    "/usr/bin/app --verbose",
+				"Restart":      "on-failure",
+			},
+			`remote "origin"`: map[string]interface{}{"url": "https://github.com/foo/bar.git"},
+		}, content)
+	})
+}
+
+func TestIniPatch(t *testing.T) {
+	t.Run("Keep comments, order & untouched lines", func(t *testing.T) {
+		testContent := []struct {
+			original string
+			content  map[string]interface{}
+			expected string
+		}{
+			{
+				original: "[Unit]\n# started after the network\nDescription=My service\n\n[Service]\nExecStartPre=/bin/a\nExecStart=/usr/bin/app\nRestart=no\n\n[Install]\nWantedBy=multi-user.target\n",
+				content: map[string]interface{}{
+					"Unit": map[string]interface{}{"Description": "My service"},
+					"Service": map[string]interface{}{
+						"ExecStartPre": []interface{}{"/bin/a", "/bin/b"},
+						"ExecStart":    "/usr/bin/app",
+						"Restart":      "always",
+						"User":         "app",
+					},
+					"Install": map[string]interface{}{"WantedBy": "multi-user.target"},
+				},
+				expected: "[Unit]\n# started after the network\nDescription=My service\n\n[Service]\nExecStartPre=/bin/a\nExecStartPre=/bin/b\nExecStart=/usr/bin/app\nRestart=always\nUser=app\n\n[Install]\nWantedBy=multi-user.target\n",
+			},
+			// removed keys & sections are dropped, new sections are appended
+			{
+				original: "[user]\n\tname = foo\n\temail = foo@bar.com\n[core]\n\teditor = vim\n",
+				content: map[string]interface{}{
+					"user": map[string]interface{}{"name": "foo"},
+					"pull": map[string]interface{}{"rebase": true},
+				},
+				expected: "[user]\n\tname = foo\n\n[pull]\n\trebase = true\n",
+			},
+		}
+		for _, value := range testContent {
+			out, err := iniPatch([]byte(value.original), value.content)
+			assert.NoError(t, err)
+			assert.Equal(t, value.expected, string(out))
+		}
+	})
+	t.Run("Return error when content has more than one level of sections", func(t *testing.T) {
+		_, err := iniMarshal(map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c": "d"}}})
+		assert.ErrorContains(t, err, "only one level of sections is supported")
+	})
+}
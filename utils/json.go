@@ -0,0 +1,246 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// jsonNode is the position of a value in the original file, objects keep the order of their keys
+type jsonNode struct {
+	start, end int
+	keys       []string
+	children   map[string]*jsonNode
+	elements   []*jsonNode
+}
+
+// jsonStyle is the formatting detected in the original file, comma is the separator of the elements of
+// objects and arrays written in a single line
+type jsonStyle struct {
+	indent    string
+	separator string
+	comma     string
+	multiline bool
+}
+
+func init() {
+	supportedFileExtPatch[".json"] = jsonPatch
+}
+
+// jsonPatch writes the content reusing the original file: the values that did not change are copied as they
+// are, the keys keep their order and new keys are appended at the end of their object. The indentation and
+// the trailing newline of the file are detected and reused for the values that are written
+func jsonPatch(original []byte, in interface{}) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(original))
+	root, err := parseJSONNode(decoder, original)
+	if err != nil {
+		return nil, err
+	}
+	style := detectJSONStyle(original)
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, original, root, in, style, 0); err != nil {
+		return nil, err
+	}
+	if bytes.HasSuffix(bytes.TrimRight(original, " \t"), []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func parseJSONNode(decoder *json.Decoder, original []byte) (*jsonNode, error) {
+	start := int(decoder.InputOffset())
+	for start < len(original) && strings.IndexByte(" \t\r\n,:", original[start]) >= 0 {
+		start++
+	}
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	node := &jsonNode{start: start}
+	switch token {
+	case json.Delim('{'):
+		node.children = map[string]*jsonNode{}
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := keyToken.(string)
+			if !ok {
+				return nil, errors.New("JSON object keys must be strings")
+			}
+			child, err := parseJSONNode(decoder, original)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := node.children[key]; !ok {
+				node.keys = append(node.keys, key)
+			}
+			node.children[key] = child
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+	case json.Delim('['):
+		for decoder.More() {
+			element, err := parseJSONNode(decoder, original)
+			if err != nil {
+				return nil, err
+			}
+			node.elements = append(node.elements, element)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+	}
+	node.end = int(decoder.InputOffset())
+	return node, nil
+}
+
+// detectJSONStyle returns the indentation of the first indented line and the separator used between keys
+// and values, files written in a single line are kept in a single line
+func detectJSONStyle(original []byte) jsonStyle {
+	style := jsonStyle{indent: "  ", separator: ":", comma: ","}
+	lines := strings.Split(string(original), "\n")
+	for _, line := range lines[1:] {
+		text := strings.TrimLeft(line, " \t")
+		if text != "" && len(text) < len(line) {
+			style.indent = line[:len(line)-len(text)]
+			break
+		}
+	}
+	style.multiline = len(strings.TrimSpace(string(original))) > 0 && strings.Contains(strings.TrimSpace(string(original)), "\n")
+	if bytes.Contains(original, []byte("\": ")) {
+		style.separator = ": "
+	}
+	return style
+}
+
+// inlineJSONStyle returns the style of the objects and arrays written in a single line, so they are kept in a
+// single line (even in files written in several lines) with the same separator between their elements
+func inlineJSONStyle(original []byte, node *jsonNode, style jsonStyle) jsonStyle {
+	style.multiline = false
+	style.comma = ","
+	if style.separator == ": " {
+		style.comma = ", "
+	}
+	var first *jsonNode
+	if len(node.keys) > 0 {
+		first = node.children[node.keys[0]]
+	} else if len(node.elements) > 0 {
+		first = node.elements[0]
+	}
+	if first == nil || len(node.keys)+len(node.elements) < 2 {
+		return style
+	}
+	if i := bytes.IndexByte(original[first.end:node.end], ','); i >= 0 && first.end+i+1 < node.end {
+		style.comma = ","
+		if original[first.end+i+1] == ' ' {
+			style.comma = ", "
+		}
+	}
+	return style
+}
+
+// marshalJSON encodes the value without escaping HTML characters, so `&&` is not written as `\u0026\u0026`
+func marshalJSON(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func writeJSON(buf *bytes.Buffer, original []byte, node *jsonNode, value interface{}, style jsonStyle, depth int) error {
+	if node != nil {
+		var current interface{}
+		if json.Unmarshal(original[node.start:node.end], &current) == nil && jsonEqual(current, value) {
+			buf.Write(original[node.start:node.end])
+			return nil
+		}
+		if (node.children != nil || node.elements != nil) && !bytes.Contains(original[node.start:node.end], []byte("\n")) {
+			style = inlineJSONStyle(original, node, style)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		var keys []string
+		var children map[string]*jsonNode
+		if node != nil && node.children != nil {
+			children = node.children
+			for _, k := range node.keys {
+				if _, ok := v[k]; ok {
+					keys = append(keys, k)
+				}
+			}
+		}
+		for _, k := range sortedKeys(v) {
+			if _, ok := children[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteString(style.comma)
+			}
+			writeJSONNewline(buf, style, depth+1)
+			keyB, err := marshalJSON(k)
+			if err != nil {
+				return err
+			}
+			buf.Write(keyB)
+			buf.WriteString(style.separator)
+			if err := writeJSON(buf, original, children[k], v[k], style, depth+1); err != nil {
+				return err
+			}
+		}
+		if len(keys) > 0 {
+			writeJSONNewline(buf, style, depth)
+		}
+		buf.WriteByte('}')
+		return nil
+	case []interface{}:
+		var elements []*jsonNode
+		if node != nil {
+			elements = node.elements
+		}
+		buf.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				buf.WriteString(style.comma)
+			}
+			writeJSONNewline(buf, style, depth+1)
+			var element *jsonNode
+			if i < len(elements) {
+				element = elements[i]
+			}
+			if err := writeJSON(buf, original, element, e, style, depth+1); err != nil {
+				return err
+			}
+		}
+		if len(v) > 0 {
+			writeJSONNewline(buf, style, depth)
+		}
+		buf.WriteByte(']')
+		return nil
+	}
+	b, err := marshalJSON(value)
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
+
+func writeJSONNewline(buf *bytes.Buffer, style jsonStyle, depth int) {
+	if !style.multiline {
+		return
+	}
+	buf.WriteByte('\n')
+	buf.WriteString(strings.Repeat(style.indent, depth))
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonPatch(t *testing.T) {
+	t.Run("Keep key order, indentation & trailing newline", func(t *testing.T) {
+		testContent := []struct {
+			original string
+			content  map[string]interface{}
+			expected string
+		}{
+			{
+				original: "{\n  \"name\": \"app\",\n  \"version\": \"1.0.0\",\n  \"scripts\": {\n    \"test\": \"jest\",\n    \"build\": \"tsc\"\n  },\n  \"files\": [\"dist\"]\n}\n",
+				content: map[string]interface{}{
+					"name":    "app",
+					"version": "1.1.0",
+					"scripts": map[string]interface{}{"test": "jest", "build": "tsc", "lint": "eslint ."},
+					"files":   []interface{}{"dist"},
+					"private": true,
+				},
+				expected: "{\n  \"name\": \"app\",\n  \"version\": \"1.1.0\",\n  \"scripts\": {\n    \"test\": \"jest\",\n    \"build\": \"tsc\",\n    \"lint\": \"eslint .\"\n  },\n  \"files\": [\"dist\"],\n  \"private\": true\n}\n",
+			},
+			{
+				original: "{\n\t\"compilerOptions\": {\n\t\t\"strict\": true,\n\t\t\"target\": \"es2017\"\n\t}\n}",
+				content: map[string]interface{}{
+					"compilerOptions": map[string]interface{}{"target": "es2022", "paths": map[string]interface{}{"@/*": []interface{}{"src/*"}}},
+				},
+				expected: "{\n\t\"compilerOptions\": {\n\t\t\"target\": \"es2022\",\n\t\t\"paths\": {\n\t\t\t\"@/*\": [\n\t\t\t\t\"src/*\"\n\t\t\t]\n\t\t}\n\t}\n}",
+			},
+			{
+				original: `{"b":1,"a":{"c":2.50}}`,
+				content:  map[string]interface{}{"b": float64(2), "a": map[string]interface{}{"c": 2.5}, "d": "e"},
+				expected: `{"b":2,"a":{"c":2.50},"d":"e"}`,
+			},
+		}
+		for _, value := range testContent {
+			out, err := jsonPatch([]byte(value.original), value.content)
+			assert.NoError(t, err)
+			assert.Equal(t, value.expected, string(out))
+		}
+	})
+	t.Run("Keep inline objects in a single line & write strings without escaping HTML", func(t *testing.T) {
+		original := "{\n  \"scripts\": {\"test\": \"jest\", \"build\": \"tsc\"},\n  \"files\": [\"dist\"],\n  \"engines\": {\"node\": \">=18\"}\n}\n"
+		content := map[string]interface{}{
+			"scripts": map[string]interface{}{"test": "jest && eslint", "build": "tsc", "lint": "eslint <src>"},
+			"files":   []interface{}{"dist", "lib"},
+			"engines": map[string]interface{}{"node": ">=18"},
+		}
+		out, err := jsonPatch([]byte(original), content)
+		assert.NoError(t, err)
+		assert.Equal(t, "{\n  \"scripts\": {\"test\": \"jest && eslint\", \"build\": \"tsc\", \"lint\": \"eslint <src>\"},\n  \"files\": [\"dist\", \"lib\"],\n  \"engines\": {\"node\": \">=18\"}\n}\n", string(out))
+	})
+}
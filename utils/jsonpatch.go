@@ -0,0 +1,257 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JSON Patch (RFC 6902) operations are applied to the decoded document, so they can be used with every
+// format handled as a JSON object (json, yaml, toml, ini, xml and hcl). Paths are JSON Pointers (RFC 6901)
+
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// WithPatch sets the JSON Patch (RFC 6902) applied to the file once items are merged, the patch is a JSON
+// encoded list of operations
+func WithPatch(patch string) func(*Transformer) {
+	return func(m *Transformer) {
+		m.patch = patch
+	}
+}
+
+// ApplyPatch applies the JSON Patch (RFC 6902) operations to the document, the operations are applied in
+// order and the document is left untouched when any of them fails (a `test` that doesn't match included)
+func ApplyPatch(document map[string]interface{}, patch string) (map[string]interface{}, error) {
+	var operations []patchOperation
+	if err := json.Unmarshal([]byte(patch), &operations); err != nil {
+		return nil, fmt.Errorf("Patch is malformed, it must be a list of JSON Patch operations: %s", err.Error())
+	}
+	var doc interface{} = deepCopy(document)
+	for i, op := range operations {
+		var err error
+		if doc, err = applyPatchOperation(doc, op); err != nil {
+			return nil, fmt.Errorf("Patch operation %d (%s) failed: %s", i, op.Op, err.Error())
+		}
+	}
+	result, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Patch must keep the document as an object")
+	}
+	return result, nil
+}
+
+func applyPatchOperation(doc interface{}, op patchOperation) (interface{}, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("path is missing")
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+	// the value is decoded separately, as a null value is not the same as a missing value
+	var opValue interface{}
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("value is missing")
+		}
+		if err := json.Unmarshal(op.Value, &opValue); err != nil {
+			return nil, err
+		}
+	case "move", "copy":
+		if op.From == nil {
+			return nil, fmt.Errorf("from is missing")
+		}
+	}
+
+	switch op.Op {
+	case "add":
+		return pointerAdd(doc, path, opValue)
+	case "remove":
+		doc, _, err := pointerRemove(doc, path)
+		return doc, err
+	case "replace":
+		if _, err := pointerGet(doc, path); err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return opValue, nil
+		}
+		if doc, _, err = pointerRemove(doc, path); err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, opValue)
+	case "move":
+		from, err := parsePointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+		if len(path) > len(from) && isPathPrefix(from, path) {
+			return nil, fmt.Errorf("%s can't be moved to one of its children", *op.From)
+		}
+		doc, value, err := pointerRemove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, value)
+	case "copy":
+		from, err := parsePointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := pointerGet(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, deepCopy(value))
+	case "test":
+		value, err := pointerGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(value, opValue) {
+			actual, _ := json.Marshal(value)
+			expected, _ := json.Marshal(opValue)
+			return nil, fmt.Errorf("value of %s is %s, expected %s", *op.Path, actual, expected)
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("operation %q is not supported", op.Op)
+}
+
+// parsePointer splits the JSON Pointer into its reference tokens, unescaping `~1` and `~0`
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("path %q must start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func pointerGet(doc interface{}, path []string) (interface{}, error) {
+	current := doc
+	for i, token := range path {
+		switch value := current.(type) {
+		case map[string]interface{}:
+			v, ok := value[token]
+			if !ok {
+				return nil, fmt.Errorf("%s does not exist", pointerString(path[:i+1]))
+			}
+			current = v
+		case []interface{}:
+			index, err := arrayIndex(token, len(value)-1)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", pointerString(path[:i+1]), err.Error())
+			}
+			current = value[index]
+		default:
+			return nil, fmt.Errorf("%s does not exist", pointerString(path[:i+1]))
+		}
+	}
+	return current, nil
+}
+
+// pointerAdd adds the value at the path, the container of the value must exist. Arrays are copied since
+// inserting an element may reallocate them, so the (possibly new) document is returned
+func pointerAdd(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := pointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		container[token] = value
+		return doc, nil
+	case []interface{}:
+		index := len(container)
+		if token != "-" {
+			if index, err = arrayIndex(token, len(container)); err != nil {
+				return nil, fmt.Errorf("%s: %s", pointerString(path), err.Error())
+			}
+		}
+		updated := append(append(append([]interface{}{}, container[:index]...), value), container[index:]...)
+		return pointerSet(doc, path[:len(path)-1], updated)
+	}
+	return nil, fmt.Errorf("%s is not an object or an array", pointerString(path[:len(path)-1]))
+}
+
+// pointerRemove removes the value at the path and returns it, together with the updated document
+func pointerRemove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("the whole document can't be removed")
+	}
+	value, err := pointerGet(doc, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	parent, _ := pointerGet(doc, path[:len(path)-1])
+	token := path[len(path)-1]
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		delete(container, token)
+		return doc, value, nil
+	case []interface{}:
+		index, _ := arrayIndex(token, len(container)-1)
+		updated := append(append([]interface{}{}, container[:index]...), container[index+1:]...)
+		doc, err := pointerSet(doc, path[:len(path)-1], updated)
+		return doc, value, err
+	}
+	return nil, nil, fmt.Errorf("%s is not an object or an array", pointerString(path[:len(path)-1]))
+}
+
+// pointerSet replaces the value at the path, which must exist
+func pointerSet(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := pointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		container[token] = value
+	case []interface{}:
+		index, _ := arrayIndex(token, len(container)-1)
+		container[index] = value
+	}
+	return doc, nil
+}
+
+// arrayIndex parses the array index, which must be between 0 and max
+func arrayIndex(token string, max int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%q is not a valid array index", token)
+	}
+	if index > max {
+		return 0, fmt.Errorf("index %d is out of bounds", index)
+	}
+	return index, nil
+}
+
+func pointerString(path []string) string {
+	var b strings.Builder
+	for _, token := range path {
+		b.WriteString("/")
+		b.WriteString(strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1"))
+	}
+	return b.String()
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyPatch(t *testing.T) {
+	t.Run("Apply JSON Patch operations", func(t *testing.T) {
+		testContent := []struct {
+			doc      string
+			patch    string
+			expected string
+		}{
+			{doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/baz","value":"qux"}]`, expected: `{"baz":"qux","foo":"bar"}`},
+			{doc: `{"foo":["bar","baz"]}`, patch: `[{"op":"add","path":"/foo/1","value":"qux"}]`, expected: `{"foo":["bar","qux","baz"]}`},
+			{doc: `{"foo":["bar"]}`, patch: `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, expected: `{"foo":["bar",["abc","def"]]}`},
+			{doc: `{"baz":"qux","foo":"bar"}`, patch: `[{"op":"remove","path":"/baz"}]`, expected: `{"foo":"bar"}`},
+			{doc: `{"foo":["bar","qux","baz"]}`, patch: `[{"op":"remove","path":"/foo/1"}]`, expected: `{"foo":["bar","baz"]}`},
+			{doc: `{"baz":"qux","foo":"bar"}`, patch: `[{"op":"replace","path":"/baz","value":null}]`, expected: `{"baz":null,"foo":"bar"}`},
+			{doc: `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, patch: `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, expected: `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+			{doc: `{"foo":["all","grass","cows","eat"]}`, patch: `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, expected: `{"foo":["all","cows","eat","grass"]}`},
+			{doc: `{"foo":{"bar":1}}`, patch: `[{"op":"copy","from":"/foo","path":"/baz"},{"op":"replace","path":"/baz/bar","value":2}]`, expected: `{"baz":{"bar":2},"foo":{"bar":1}}`},
+			{doc: `{"a/b":1,"m~n":2}`, patch: `[{"op":"test","path":"/a~1b","value":1},{"op":"remove","path":"/m~0n"}]`, expected: `{"a/b":1}`},
+		}
+		for _, value := range testContent {
+			var doc, expected map[string]interface{}
+			json.Unmarshal([]byte(value.doc), &doc)
+			json.Unmarshal([]byte(value.expected), &expected)
+			outcome, err := ApplyPatch(doc, value.patch)
+			assert.NoError(t, err, value.patch)
+			assert.Equal(t, expected, outcome, value.patch)
+		}
+	})
+	t.Run("Return error when an operation fails", func(t *testing.T) {
+		testContent := []struct {
+			patch         string
+			expectedError string
+		}{
+			{patch: `[{"op":"test","path":"/foo/0","value":"baz"}]`, expectedError: `value of /foo/0 is "bar", expected "baz"`},
+			{patch: `[{"op":"remove","path":"/missing"}]`, expectedError: "/missing does not exist"},
+			{patch: `[{"op":"add","path":"/foo/5","value":1}]`, expectedError: "index 5 is out of bounds"},
+			{patch: `[{"op":"add","path":"/foo/01","value":1}]`, expectedError: `"01" is not a valid array index`},
+			{patch: `[{"op":"add","path":"/a/b","value":1}]`, expectedError: "/a does not exist"},
+			{patch: `[{"op":"add","path":"/a"}]`, expectedError: "value is missing"},
+			{patch: `[{"op":"move","from":"/foo","path":"/foo/0"}]`, expectedError: "can't be moved to one of its children"},
+			{patch: `[{"op":"rename","path":"/foo"}]`, expectedError: `operation "rename" is not supported`},
+			{patch: `[{"op":"replace","path":"","value":[]}]`, expectedError: "Patch must keep the document as an object"},
+			{patch: `{"op":"remove","path":"/foo"}`, expectedError: "Patch is malformed"},
+		}
+		for _, value := range testContent {
+			doc := map[string]interface{}{"foo": []interface{}{"bar"}}
+			_, err := ApplyPatch(doc, value.patch)
+			assert.ErrorContains(t, err, value.expectedError, value.patch)
+			assert.Equal(t, map[string]interface{}{"foo": []interface{}{"bar"}}, doc)
+		}
+	})
+}
@@ -3,12 +3,66 @@ package utils
 import (
 	"fmt"
 	"reflect"
+	"strings"
 )
 
+// Merge strategies, they decide how src is applied to dst
+const (
+	// DeepMergeStrategy adds and overwrites keys, see DeepMerge
+	DeepMergeStrategy = "deep_merge"
+	// MergePatchStrategy applies src as a JSON Merge Patch (RFC 7396), null values remove keys, see MergePatch
+	MergePatchStrategy = "merge_patch"
+)
+
+// Array strategies, they decide how the arrays of src are merged with the arrays of dst
+const (
+	// ArrayReplace replaces the array of dst by the array of src
+	ArrayReplace = "replace"
+	// ArrayAppend appends the elements of src to the array of dst
+	ArrayAppend = "append"
+	// ArrayUnion handles arrays as sets, elements of src are appended unless dst holds an equal element.
+	// Repeated elements are removed and the first occurrence keeps its position
+	ArrayUnion = "union"
+	// ArrayIndex merges arrays position by position, element i of src is deep merged into element i of dst
+	// and the elements of src beyond the length of dst are appended
+	ArrayIndex = "index"
+	// ArrayByKey deep merges the elements of src into the element of dst with the same value of a key field,
+	// the other elements are appended
+	ArrayByKey = "by-key"
+)
+
+// Rule strategies, besides the array strategies (ArrayAppend, ArrayUnion, ArrayIndex and ArrayByKey)
+const (
+	// RuleOverride replaces the value of dst by the value of src, whatever its type is
+	RuleOverride = "override"
+	// RuleKeepExisting keeps the value of dst, the value of src is only set when dst has no value
+	RuleKeepExisting = "keep-existing"
+)
+
+// MergeRule assigns a strategy to the values whose path matches the selector
+type MergeRule struct {
+	// Path is the selector, keys joined with dots where `*` matches any key (e.g. `services.*.environment`).
+	// A leading `$.` and `[*]` are allowed, the elements of an array have the path of their array
+	Path string
+	// Strategy is one of RuleOverride, RuleKeepExisting, ArrayAppend, ArrayUnion, ArrayIndex or ArrayByKey
+	Strategy string
+	// Key is the field identifying the elements of arrays merged with ArrayByKey
+	Key string
+}
+
 type Mergito struct {
 	Src           any
 	Dst           any
 	OverrideArray bool
+	Strategy      string
+	// ArrayStrategy is the strategy used to merge arrays, when it's empty OverrideArray decides
+	// whether arrays are replaced or appended
+	ArrayStrategy string
+	// Rules assign strategies to paths, the first rule matching a path is used, see WithRules
+	Rules []MergeRule
+	// ArrayMergeKeys maps the path of arrays of objects (keys joined with dots, e.g. `spec.containers`)
+	// to the field identifying their elements, see WithMergeKeys
+	ArrayMergeKeys map[string]string
 }
 
 func WithOverrideArray(append bool) func(*Mergito) {
@@ -17,16 +71,79 @@ func WithOverrideArray(append bool) func(*Mergito) {
 	}
 }
 
+// WithArrayStrategy sets how arrays are merged (ArrayReplace, ArrayAppend, ArrayUnion or ArrayIndex), it takes
+// precedence over WithOverrideArray
+func WithArrayStrategy(strategy string) func(*Mergito) {
+	return func(m *Mergito) {
+		m.ArrayStrategy = strategy
+	}
+}
+
+// WithRules sets the rules assigning strategies to the values placed in the paths matched by their
+// selector, they take precedence over every other setting
+func WithRules(rules []MergeRule) func(*Mergito) {
+	return func(m *Mergito) {
+		m.Rules = rules
+	}
+}
+
+// WithStrategy sets the merge strategy, defaults to DeepMergeStrategy
+func WithStrategy(strategy string) func(*Mergito) {
+	return func(m *Mergito) {
+		m.Strategy = strategy
+	}
+}
+
+// WithMergeKeys sets the field identifying the elements of the arrays placed in the given paths,
+// elements of src whose field has the same value as an element of dst are deep merged into it and the
+// other elements are appended. The keys of the path are joined with dots, array elements have the path
+// of their array (e.g. `spec.containers.ports` are the ports of every container)
+func WithMergeKeys(keys map[string]string) func(*Mergito) {
+	return func(m *Mergito) {
+		m.ArrayMergeKeys = keys
+	}
+}
+
 func Merge(src any, dst any, options ...func(*Mergito)) (any, error) {
-	m := &Mergito{Src: src, Dst: dst, OverrideArray: false}
+	m := &Mergito{Src: src, Dst: dst, OverrideArray: false, Strategy: DeepMergeStrategy}
 	for _, opt := range options {
 		opt(m)
 	}
-	a, err := DeepMerge(reflect.ValueOf(m.Src), reflect.ValueOf(m.Dst), m.OverrideArray)
+	if m.Strategy == MergePatchStrategy {
+		return MergePatch(m.Src, m.Dst), nil
+	}
+	a, err := m.deepMerge(reflect.ValueOf(m.Src), reflect.ValueOf(m.Dst), nil)
 	return a, err
 }
 
+// MergePatch applies the patch to dst following RFC 7396: keys whose value is null are removed, objects
+// are patched recursively and any other value (arrays included) replaces the value of dst
+func MergePatch(patch any, dst any) any {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	dstMap, ok := dst.(map[string]interface{})
+	if !ok {
+		dstMap = map[string]interface{}{}
+	}
+	for k, v := range patchMap {
+		if v == nil {
+			delete(dstMap, k)
+			continue
+		}
+		dstMap[k] = MergePatch(v, dstMap[k])
+	}
+	return dstMap
+}
+
 func DeepMerge(src, dst reflect.Value, overrideArray bool) (any, error) {
+	m := &Mergito{OverrideArray: overrideArray}
+	return m.deepMerge(src, dst, nil)
+}
+
+// deepMerge merges src into dst, path holds the keys of the maps being merged
+func (m *Mergito) deepMerge(src, dst reflect.Value, path []string) (any, error) {
 	if src.Kind() != reflect.Map || dst.Kind() != reflect.Map {
 		return dst.Interface(), nil
 	}
@@ -43,8 +160,26 @@ func DeepMerge(src, dst reflect.Value, overrideArray bool) (any, error) {
 		// dstMapValue.Kind() will return reflect.Invalid type
 		dstMapValue := dst.MapIndex(srcMapKey)
 
+		// rules replacing or keeping the whole value are applied before merging it
+		if rule, ok := m.ruleAt(childPath(path, srcMapKey)); ok {
+			switch rule.Strategy {
+			case RuleKeepExisting:
+				if dstMapValue.Kind() == reflect.Invalid || dstMapValue.Kind() == reflect.Interface && dstMapValue.IsNil() {
+					dst.SetMapIndex(srcMapKey, srcMapValue)
+				}
+				continue
+			case RuleOverride:
+				dst.SetMapIndex(srcMapKey, srcMapValue)
+				continue
+			}
+		}
+
 		switch {
 
+		// a null (or empty XML element) in the destination map is replaced by the value of src map
+		case dstMapValue.Kind() == reflect.Interface && dstMapValue.IsNil():
+			dst.SetMapIndex(srcMapKey, srcMapValue)
+			continue
 		case srcMapValue.Kind() == reflect.Map && dstMapValue.Kind() != reflect.Invalid:
 			//verify if the data type of both maps is the same, if not an error is returned
 			if s := dataTypeValidation(srcMapValue.Type(), dstMapValue.Elem().Type()); s != "" {
@@ -52,11 +187,31 @@ func DeepMerge(src, dst reflect.Value, overrideArray bool) (any, error) {
 			}
 			//if the elements are a map, we call the function recursively until we reach the level
 			//where the elements are primitive types
-			DeepMerge(srcMapValue, dstMapValue.Elem(), overrideArray)
+			if _, err := m.deepMerge(srcMapValue, dstMapValue.Elem(), childPath(path, srcMapKey)); err != nil {
+				return nil, err
+			}
 			continue
 		case srcMapValue.Kind() == reflect.Slice && dstMapValue.Kind() != reflect.Invalid:
-			// if overrideArray is true, we don't merge(join) array content, instead we override
-			if overrideArray {
+			strategy, key, err := m.arrayStrategyAt(childPath(path, srcMapKey))
+			if err != nil {
+				return nil, err
+			}
+			// arrays of objects are merged element by element, elements are identified by the key field
+			if strategy == ArrayByKey {
+				srcSlice, srcIsSlice := srcMapValue.Interface().([]interface{})
+				dstSlice, dstIsSlice := dstMapValue.Elem().Interface().([]interface{})
+				if srcIsSlice && dstIsSlice {
+					merged, err := m.mergeArrayByKey(srcSlice, dstSlice, key, childPath(path, srcMapKey))
+					if err != nil {
+						return nil, err
+					}
+					dst.SetMapIndex(srcMapKey, reflect.ValueOf(merged))
+					continue
+				}
+				strategy = ArrayAppend
+			}
+			// if the strategy is replace, we don't merge(join) array content, instead we override
+			if strategy == ArrayReplace {
 				dst.SetMapIndex(srcMapKey, srcMapValue)
 				continue
 			}
@@ -64,7 +219,19 @@ func DeepMerge(src, dst reflect.Value, overrideArray bool) (any, error) {
 			if s := dataTypeValidation(srcMapValue.Type(), dstMapValue.Elem().Type()); s != "" {
 				return nil, fmt.Errorf(s)
 			}
-			dst.SetMapIndex(srcMapKey, reflect.AppendSlice(dstMapValue.Elem(), srcMapValue))
+			if strategy == ArrayIndex {
+				merged, err := m.mergeArrayByIndex(srcMapValue, dstMapValue.Elem(), childPath(path, srcMapKey))
+				if err != nil {
+					return nil, err
+				}
+				dst.SetMapIndex(srcMapKey, merged)
+				continue
+			}
+			joined := reflect.AppendSlice(dstMapValue.Elem(), srcMapValue)
+			if strategy == ArrayUnion {
+				joined = uniqueElements(joined)
+			}
+			dst.SetMapIndex(srcMapKey, joined)
 			continue
 		}
 
@@ -73,6 +240,156 @@ func DeepMerge(src, dst reflect.Value, overrideArray bool) (any, error) {
 	return dst.Interface(), nil
 }
 
+// matches reports whether the path is selected by the rule
+func (r MergeRule) matches(path []string) bool {
+	selector := strings.ReplaceAll(strings.TrimPrefix(strings.TrimPrefix(r.Path, "$"), "."), "[*]", "")
+	segments := strings.Split(selector, ".")
+	if len(segments) != len(path) {
+		return false
+	}
+	for i, segment := range segments {
+		if segment != "*" && segment != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ruleAt returns the first rule matching the path
+func (m *Mergito) ruleAt(path []string) (MergeRule, bool) {
+	for _, rule := range m.Rules {
+		if rule.matches(path) {
+			return rule, true
+		}
+	}
+	return MergeRule{}, false
+}
+
+// arrayStrategyAt returns the strategy of the array placed in the path, together with the key field of its
+// elements. Rules come first, then array merge keys and then the array strategy of the merge
+func (m *Mergito) arrayStrategyAt(path []string) (string, string, error) {
+	if rule, ok := m.ruleAt(path); ok {
+		switch rule.Strategy {
+		case ArrayByKey:
+			if rule.Key == "" {
+				return "", "", fmt.Errorf("Merge rule %s uses the %s strategy, but it has no key", rule.Path, ArrayByKey)
+			}
+			return ArrayByKey, rule.Key, nil
+		case RuleOverride:
+			return ArrayReplace, "", nil
+		case ArrayAppend, ArrayUnion, ArrayIndex, ArrayReplace:
+			return rule.Strategy, "", nil
+		}
+		return "", "", fmt.Errorf("Merge rule %s uses the %s strategy, which is not supported", rule.Path, rule.Strategy)
+	}
+	if key, ok := m.ArrayMergeKeys[strings.Join(path, ".")]; ok {
+		return ArrayByKey, key, nil
+	}
+	return arrayStrategy(m.ArrayStrategy, m.OverrideArray), "", nil
+}
+
+// arrayStrategy returns the array strategy in use, overrideArray decides when no strategy is set
+func arrayStrategy(strategy string, overrideArray bool) string {
+	switch {
+	case strategy != "":
+		return strategy
+	case overrideArray:
+		return ArrayReplace
+	}
+	return ArrayAppend
+}
+
+// uniqueElements removes the elements of the slice equal to a previous element
+func uniqueElements(slice reflect.Value) reflect.Value {
+	unique := reflect.MakeSlice(slice.Type(), 0, slice.Len())
+	for i := 0; i < slice.Len(); i++ {
+		element := slice.Index(i)
+		repeated := false
+		for j := 0; j < unique.Len(); j++ {
+			if jsonEqual(unique.Index(j).Interface(), element.Interface()) {
+				repeated = true
+				break
+			}
+		}
+		if !repeated {
+			unique = reflect.Append(unique, element)
+		}
+	}
+	return unique
+}
+
+// mergeArrayByIndex deep merges each element of src into the element of dst placed in the same position,
+// nested arrays are merged by index too and any other element of src replaces the one of dst
+func (m *Mergito) mergeArrayByIndex(src, dst reflect.Value, path []string) (reflect.Value, error) {
+	merged := reflect.MakeSlice(dst.Type(), dst.Len(), dst.Len())
+	reflect.Copy(merged, dst)
+	for i := 0; i < src.Len(); i++ {
+		srcElement := src.Index(i)
+		if i >= merged.Len() {
+			merged = reflect.Append(merged, srcElement)
+			continue
+		}
+		srcValue := reflect.ValueOf(srcElement.Interface())
+		dstValue := reflect.ValueOf(merged.Index(i).Interface())
+		switch {
+		case srcValue.Kind() == reflect.Map && dstValue.Kind() == reflect.Map:
+			if _, err := m.deepMerge(srcValue, dstValue, path); err != nil {
+				return reflect.Value{}, err
+			}
+		case srcValue.Kind() == reflect.Slice && dstValue.Kind() == reflect.Slice && srcValue.Type() == dstValue.Type():
+			element, err := m.mergeArrayByIndex(srcValue, dstValue, path)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			merged.Index(i).Set(element)
+		default:
+			merged.Index(i).Set(srcElement)
+		}
+	}
+	return merged, nil
+}
+
+// mergeArrayByKey deep merges the elements of src into the elements of dst identified by the same value of
+// the key field, elements of src that don't match any element of dst are appended
+func (m *Mergito) mergeArrayByKey(src, dst []interface{}, key string, path []string) ([]interface{}, error) {
+	merged := append([]interface{}{}, dst...)
+	for _, srcElement := range src {
+		if i := indexByKey(merged, srcElement, key); i >= 0 {
+			if _, err := m.deepMerge(reflect.ValueOf(srcElement), reflect.ValueOf(merged[i]), path); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		merged = append(merged, srcElement)
+	}
+	return merged, nil
+}
+
+// indexByKey returns the index of the object of elements whose key field has the same value as the one of
+// element, -1 is returned when there's no such object or element has no key field
+func indexByKey(elements []interface{}, element interface{}, key string) int {
+	object, ok := element.(map[string]interface{})
+	if !ok {
+		return -1
+	}
+	value, ok := object[key]
+	if !ok {
+		return -1
+	}
+	for i, e := range elements {
+		if candidate, ok := e.(map[string]interface{}); ok {
+			if candidateValue, ok := candidate[key]; ok && jsonEqual(candidateValue, value) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func childPath(path []string, key reflect.Value) []string {
+	return append(append([]string{}, path...), fmt.Sprint(key.Interface()))
+}
+
 func dataTypeValidation(src, dst reflect.Type) string {
 	if src != dst {
 		return fmt.Sprintf("Cannot append two %ss with different types (%s, %s)", src.Kind(), src, dst)
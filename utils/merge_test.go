@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -288,3 +289,198 @@ func TestEmptySource(t *testing.T) {
 		assert.Equal(t, dst, outcome)
 	})
 }
+
+func TestNullDestination(t *testing.T) {
+	t.Run("Replace null values of dst with the content of src", func(t *testing.T) {
+		dst := map[string]interface{}{
+			"aka":    "blues",
+			"planet": nil,
+		}
+		outcome, err := Merge(map[string]interface{}{"planet": map[string]interface{}{"mars": "7777.9"}}, dst)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"aka":    "blues",
+			"planet": map[string]interface{}{"mars": "7777.9"},
+		}, outcome)
+	})
+	t.Run("Return error of deep level items", func(t *testing.T) {
+		dst := map[string]interface{}{
+			"planet": map[string]interface{}{
+				"mars": []int{12},
+			},
+		}
+		_, err := Merge(map[string]interface{}{"planet": map[string]interface{}{"mars": []string{"a"}}}, dst)
+		assert.ErrorContains(t, err, "Cannot append two slices with different type")
+	})
+}
+
+func TestMergePatch(t *testing.T) {
+	t.Run("Apply the merge patch following RFC 7396", func(t *testing.T) {
+		testElem := []struct {
+			patch    string
+			dst      string
+			expected string
+		}{
+			{patch: `{"a":"z"}`, dst: `{"a":"b"}`, expected: `{"a":"z"}`},
+			{patch: `{"a":null}`, dst: `{"a":"b","b":"c"}`, expected: `{"b":"c"}`},
+			{patch: `{"a":["b"]}`, dst: `{"a":[{"b":"c"}]}`, expected: `{"a":["b"]}`},
+			{patch: `{"a":{"b":"d","c":null}}`, dst: `{"a":{"b":"c","c":"d"}}`, expected: `{"a":{"b":"d"}}`},
+			{patch: `{"a":{"bb":{"ccc":null}}}`, dst: `{}`, expected: `{"a":{"bb":{}}}`},
+			{patch: `{"a":{"b":"c"}}`, dst: `{"a":"b"}`, expected: `{"a":{"b":"c"}}`},
+			{patch: `{"missing":null}`, dst: `{"e":null}`, expected: `{"e":null}`},
+		}
+		for _, value := range testElem {
+			var patch, dst, expected map[string]interface{}
+			json.Unmarshal([]byte(value.patch), &patch)
+			json.Unmarshal([]byte(value.dst), &dst)
+			json.Unmarshal([]byte(value.expected), &expected)
+			outcome, err := Merge(patch, dst, WithStrategy(MergePatchStrategy))
+			assert.NoError(t, err)
+			assert.Equal(t, expected, outcome, value.patch)
+		}
+	})
+}
+
+func TestMergeArrayByKey(t *testing.T) {
+	t.Run("Merge the elements of arrays with the same key field", func(t *testing.T) {
+		testElem := []struct {
+			src       string
+			dst       string
+			mergeKeys map[string]string
+			expected  string
+		}{
+			{
+				src:       `{"spec":{"containers":[{"name":"api","image":"api:v2"},{"name":"sidecar","image":"envoy"}]}}`,
+				dst:       `{"spec":{"containers":[{"name":"api","image":"api:v1","ports":[80]}]}}`,
+				mergeKeys: map[string]string{"spec.containers": "name"},
+				expected:  `{"spec":{"containers":[{"name":"api","image":"api:v2","ports":[80]},{"name":"sidecar","image":"envoy"}]}}`,
+			},
+			{
+				src:       `{"containers":[{"name":"api","ports":[{"containerPort":80,"protocol":"UDP"},{"containerPort":443}]}]}`,
+				dst:       `{"containers":[{"name":"db"},{"name":"api","ports":[{"containerPort":80,"protocol":"TCP"}]}]}`,
+				mergeKeys: map[string]string{"containers": "name", "containers.ports": "containerPort"},
+				expected:  `{"containers":[{"name":"db"},{"name":"api","ports":[{"containerPort":80,"protocol":"UDP"},{"containerPort":443}]}]}`,
+			},
+			{
+				src:       `{"ports":["8080:80",{"target":443,"published":8443}]}`,
+				dst:       `{"ports":[{"target":443,"published":443}]}`,
+				mergeKeys: map[string]string{"ports": "target"},
+				expected:  `{"ports":[{"target":443,"published":8443},"8080:80"]}`,
+			},
+		}
+		for _, value := range testElem {
+			var src, dst, expected map[string]interface{}
+			json.Unmarshal([]byte(value.src), &src)
+			json.Unmarshal([]byte(value.dst), &dst)
+			json.Unmarshal([]byte(value.expected), &expected)
+			outcome, err := Merge(src, dst, WithOverrideArray(true), WithMergeKeys(value.mergeKeys))
+			assert.NoError(t, err)
+			assert.Equal(t, expected, outcome, value.src)
+		}
+	})
+}
+
+func TestMergeArrayUnion(t *testing.T) {
+	t.Run("Merge arrays as sets keeping the first occurrence of each element", func(t *testing.T) {
+		testElem := []struct {
+			src      map[string]interface{}
+			dst      map[string]interface{}
+			expected map[string]interface{}
+		}{
+			{
+				src:      map[string]interface{}{"environment": []interface{}{"DEBUG=1", "PORT=80"}},
+				dst:      map[string]interface{}{"environment": []interface{}{"PORT=80", "HOST=0.0.0.0", "PORT=80"}},
+				expected: map[string]interface{}{"environment": []interface{}{"PORT=80", "HOST=0.0.0.0", "DEBUG=1"}},
+			},
+			{
+				src:      map[string]interface{}{"ports": []interface{}{map[string]interface{}{"target": 80.0}, 443.0}},
+				dst:      map[string]interface{}{"ports": []interface{}{443.0, map[string]interface{}{"target": 80.0}}},
+				expected: map[string]interface{}{"ports": []interface{}{443.0, map[string]interface{}{"target": 80.0}}},
+			},
+			{
+				src:      map[string]interface{}{"clubs": []string{"Chelsea", "City"}},
+				dst:      map[string]interface{}{"clubs": []string{"City", "Milan"}},
+				expected: map[string]interface{}{"clubs": []string{"City", "Milan", "Chelsea"}},
+			},
+		}
+		for _, value := range testElem {
+			outcome, err := Merge(value.src, value.dst, WithOverrideArray(true), WithArrayStrategy(ArrayUnion))
+			assert.NoError(t, err)
+			assert.Equal(t, value.expected, outcome)
+		}
+	})
+}
+
+func TestMergeArrayByIndex(t *testing.T) {
+	t.Run("Merge the elements of arrays placed in the same position", func(t *testing.T) {
+		testElem := []struct {
+			src      string
+			dst      string
+			expected string
+		}{
+			{
+				src:      `{"servers":[{"port":8080}]}`,
+				dst:      `{"servers":[{"host":"a","port":80},{"host":"b","port":80}]}`,
+				expected: `{"servers":[{"host":"a","port":8080},{"host":"b","port":80}]}`,
+			},
+			{
+				src:      `{"servers":[{},{"tls":true},{"host":"c"}]}`,
+				dst:      `{"servers":[{"host":"a"},{"host":"b"}]}`,
+				expected: `{"servers":[{"host":"a"},{"host":"b","tls":true},{"host":"c"}]}`,
+			},
+			{
+				src:      `{"matrix":[[1],"x"]}`,
+				dst:      `{"matrix":[[0,2],[3]]}`,
+				expected: `{"matrix":[[1,2],"x"]}`,
+			},
+		}
+		for _, value := range testElem {
+			var src, dst, expected map[string]interface{}
+			json.Unmarshal([]byte(value.src), &src)
+			json.Unmarshal([]byte(value.dst), &dst)
+			json.Unmarshal([]byte(value.expected), &expected)
+			outcome, err := Merge(src, dst, WithArrayStrategy(ArrayIndex))
+			assert.NoError(t, err)
+			assert.Equal(t, expected, outcome, value.src)
+		}
+		outcome, err := Merge(map[string]interface{}{"clubs": []string{"Chelsea"}}, map[string]interface{}{"clubs": []string{"City", "Milan"}}, WithArrayStrategy(ArrayIndex))
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"clubs": []string{"Chelsea", "Milan"}}, outcome)
+	})
+}
+
+func TestMergeRules(t *testing.T) {
+	t.Run("Apply the strategy of the first rule matching each path", func(t *testing.T) {
+		dst := `{"services":{"api":{"environment":["PORT=80"],"command":["run"],"ports":[{"target":80,"published":80}],"image":"api:v1","labels":{"team":"a"}},"db":{"environment":["PORT=80"]}}}`
+		src := `{"services":{"api":{"environment":["DEBUG=1","PORT=80"],"command":["serve"],"ports":[{"target":80,"published":8080}],"image":"api:v2","labels":{"owner":"b"}},"db":{"environment":["PORT=80"]}}}`
+		rules := []MergeRule{
+			{Path: "services.api.environment", Strategy: ArrayAppend},
+			{Path: "$.services.*.environment", Strategy: ArrayUnion},
+			{Path: "services.*.command", Strategy: RuleOverride},
+			{Path: "services.*.ports[*]", Strategy: ArrayByKey, Key: "target"},
+			{Path: "services.*.image", Strategy: RuleKeepExisting},
+			{Path: "services.*.labels", Strategy: RuleOverride},
+		}
+		expected := `{"services":{"api":{"environment":["PORT=80","DEBUG=1","PORT=80"],"command":["serve"],"ports":[{"target":80,"published":8080}],"image":"api:v1","labels":{"owner":"b"}},"db":{"environment":["PORT=80"]}}}`
+		var srcContent, dstContent, expectedContent map[string]interface{}
+		json.Unmarshal([]byte(src), &srcContent)
+		json.Unmarshal([]byte(dst), &dstContent)
+		json.Unmarshal([]byte(expected), &expectedContent)
+		outcome, err := Merge(srcContent, dstContent, WithOverrideArray(true), WithRules(rules))
+		assert.NoError(t, err)
+		assert.Equal(t, expectedContent, outcome)
+	})
+	t.Run("Keep existing values & set the missing ones", func(t *testing.T) {
+		src := map[string]interface{}{"a": "src", "b": "src", "c": "src"}
+		dst := map[string]interface{}{"a": "dst", "b": nil}
+		outcome, err := Merge(src, dst, WithRules([]MergeRule{{Path: "*", Strategy: RuleKeepExisting}}))
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"a": "dst", "b": "src", "c": "src"}, outcome)
+	})
+	t.Run("Return error when a by-key rule has no key", func(t *testing.T) {
+		src := map[string]interface{}{"a": []interface{}{"x"}}
+		dst := map[string]interface{}{"a": []interface{}{"y"}}
+		_, err := Merge(src, dst, WithRules([]MergeRule{{Path: "a", Strategy: ArrayByKey}}))
+		assert.ErrorContains(t, err, "Merge rule a uses the by-key strategy, but it has no key")
+	})
+}
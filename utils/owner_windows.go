@@ -0,0 +1,10 @@
+//go:build windows
+
+package utils
+
+import "os"
+
+// fileOwner returns the user and group that own the file, ownership isn't available on windows
+func fileOwner(info os.FileInfo) (int, int, bool) {
+	return -1, -1, false
+}
@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Symbolic link policies, they decide what happens when a file managed by the client is a symbolic link
+const (
+	// SymlinkFollow reads and writes the file the link points to, the link is kept
+	SymlinkFollow = "follow"
+	// SymlinkRefuse returns an error when the file is a symbolic link
+	SymlinkRefuse = "refuse"
+	// SymlinkReplace replaces the link by a regular file holding the result, the file the link points to is
+	// only read
+	SymlinkReplace = "replace"
+)
+
+// maxSymlinks is the number of links followed before giving up, as the links may form a loop
+const maxSymlinks = 255
+
+// ResolvePath returns the path of the file, relative paths are resolved against the base directory of the
+// client (when it's set) and symbolic links are handled according to the symlink policy of the client. An
+// error is returned when the canonical path of the file, that is the absolute path without symbolic links,
+// is not placed in one of the allowed paths
+func (cl Client) ResolvePath(path string) (string, error) {
+	resolved := cl.joinBaseDir(path)
+	target := ""
+	if info, err := os.Lstat(resolved); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		switch cl.SymlinkPolicy {
+		case SymlinkRefuse:
+			return "", fmt.Errorf("Path %s is a symbolic link, symbolic links are refused by the symlink policy", path)
+		case SymlinkReplace:
+			// the link is replaced but the file it points to is still read
+			if target, err = followSymlink(resolved); err != nil {
+				return "", err
+			}
+		default:
+			if resolved, err = followSymlink(resolved); err != nil {
+				return "", err
+			}
+		}
+	}
+	if len(cl.AllowedPaths) == 0 {
+		return resolved, nil
+	}
+	// the file itself is not resolved, as links replaced by the policy are not followed. Their target is
+	// checked too, since it's read before the link is replaced
+	canonicalDir, err := canonicalPath(filepath.Dir(resolved))
+	if err != nil {
+		return "", err
+	}
+	checked := []string{filepath.Join(canonicalDir, filepath.Base(resolved))}
+	if target != "" {
+		canonicalTarget, err := canonicalPath(target)
+		if err != nil {
+			return "", err
+		}
+		checked = append(checked, canonicalTarget)
+	}
+	for _, canonical := range checked {
+		allowed, err := cl.isAllowed(canonical)
+		if err != nil {
+			return "", err
+		}
+		if !allowed {
+			return "", fmt.Errorf("Path %s (resolved to %s) is not allowed, files must be placed in one of the following paths: %s", path, canonical, strings.Join(cl.AllowedPaths, ", "))
+		}
+	}
+	return resolved, nil
+}
+
+// isAllowed reports whether the canonical path is placed in one of the allowed paths
+func (cl Client) isAllowed(canonical string) (bool, error) {
+	for _, allowed := range cl.AllowedPaths {
+		allowedPath, err := canonicalPath(cl.joinBaseDir(allowed))
+		if err != nil {
+			return false, err
+		}
+		if isWithin(allowedPath, canonical) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (cl Client) joinBaseDir(path string) string {
+	if filepath.IsAbs(path) || cl.BaseDir == "" {
+		return path
+	}
+	return filepath.Join(cl.BaseDir, path)
+}
+
+// followSymlink returns the path the link points to, following every link of the chain. Links pointing to
+// files that don't exist yet are followed too, so the file is created where the link points
+func followSymlink(path string) (string, error) {
+	for i := 0; i < maxSymlinks; i++ {
+		info, err := os.Lstat(path)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			return path, nil
+		}
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		path = target
+	}
+	return "", fmt.Errorf("Path %s has too many levels of symbolic links", path)
+}
+
+// canonicalPath returns the absolute path without symbolic links, the parts of the path that don't exist
+// yet are appended to the canonical path of the closest existing directory
+func canonicalPath(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	missing := ""
+	for dir := absPath; ; {
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			return filepath.Join(resolved, missing), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return absPath, nil
+		}
+		missing = filepath.Join(filepath.Base(dir), missing)
+		dir = parent
+	}
+}
+
+// isWithin reports whether path is the given directory (or file) or it's placed inside of it
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
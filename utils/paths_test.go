@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolvePath(t *testing.T) {
+	t.Run("Resolve relative paths against the base directory", func(t *testing.T) {
+		cl := Client{BaseDir: "/srv/app"}
+		path, err := cl.ResolvePath("config/app.json")
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.FromSlash("/srv/app/config/app.json"), path)
+
+		path, err = cl.ResolvePath("/etc/app.json")
+		assert.NoError(t, err)
+		assert.Equal(t, "/etc/app.json", path)
+
+		path, err = Client{}.ResolvePath("./app.json")
+		assert.NoError(t, err)
+		assert.Equal(t, "./app.json", path)
+	})
+	t.Run("Return error when the path is not allowed", func(t *testing.T) {
+		cl := Client{BaseDir: "/srv/app", AllowedPaths: []string{"config", "/etc/app.json"}}
+		testContent := []struct {
+			path    string
+			allowed bool
+		}{
+			{path: "config/app.json", allowed: true},
+			{path: "config", allowed: true},
+			{path: "/etc/app.json", allowed: true},
+			{path: "config/../secrets.json", allowed: false},
+			{path: "config-backup/app.json", allowed: false},
+			{path: "/etc/passwd", allowed: false},
+		}
+		for _, value := range testContent {
+			_, err := cl.ResolvePath(value.path)
+			if value.allowed {
+				assert.NoError(t, err, value.path)
+			} else {
+				assert.Error(t, err, value.path)
+			}
+		}
+	})
+}
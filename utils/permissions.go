@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+)
+
+const (
+	defaultFilePermission      os.FileMode = 0644
+	defaultDirectoryPermission os.FileMode = 0755
+)
+
+// WithFilePermission sets the permissions of the written file. When it's not set, existing files keep
+// their permissions and new files are created with the permissions configured in the Client
+func WithFilePermission(mode os.FileMode) func(*Transformer) {
+	return func(m *Transformer) {
+		m.filePermission = mode
+	}
+}
+
+// WithDirectoryPermission sets the permissions of the directories created to hold the written file
+func WithDirectoryPermission(mode os.FileMode) func(*Transformer) {
+	return func(m *Transformer) {
+		m.directoryPermission = mode
+	}
+}
+
+// WithOwner sets the user and group that own the written file, both can be provided as names or
+// numeric ids. When they are empty, existing files keep their owner
+func WithOwner(owner, group string) func(*Transformer) {
+	return func(m *Transformer) {
+		m.owner = owner
+		m.group = group
+	}
+}
+
+// ParsePermission parses permissions written in octal notation (e.g. `0644`)
+func ParsePermission(permission string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(permission, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("%s is not a valid permission, permissions must be written in octal notation (e.g. 0644)", permission)
+	}
+	return os.FileMode(mode), nil
+}
+
+// newFilePermission returns the permissions of the files created by the transformer
+func (cl Client) newFilePermission(t Transformer) os.FileMode {
+	switch {
+	case t.filePermission != 0:
+		return t.filePermission
+	case cl.FilePermission != 0:
+		return cl.FilePermission
+	}
+	return defaultFilePermission
+}
+
+func (cl Client) directoryPermission(t Transformer) os.FileMode {
+	switch {
+	case t.directoryPermission != 0:
+		return t.directoryPermission
+	case cl.DirectoryPermission != 0:
+		return cl.DirectoryPermission
+	}
+	return defaultDirectoryPermission
+}
+
+// lookupOwner returns the numeric ids of the given user and group, -1 is returned for the empty ones
+func lookupOwner(owner, group string) (int, int, error) {
+	uid, gid := -1, -1
+	if owner != "" {
+		id := owner
+		if _, err := strconv.Atoi(owner); err != nil {
+			u, err := user.Lookup(owner)
+			if err != nil {
+				return 0, 0, err
+			}
+			id = u.Uid
+		}
+		uid, _ = strconv.Atoi(id)
+	}
+	if group != "" {
+		id := group
+		if _, err := strconv.Atoi(group); err != nil {
+			g, err := user.LookupGroup(group)
+			if err != nil {
+				return 0, 0, err
+			}
+			id = g.Gid
+		}
+		gid, _ = strconv.Atoi(id)
+	}
+	return uid, gid, nil
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePermission(t *testing.T) {
+	t.Run("Parse permissions written in octal notation", func(t *testing.T) {
+		testContent := []struct {
+			permission string
+			expected   os.FileMode
+			fails      bool
+		}{
+			{permission: "0644", expected: 0644},
+			{permission: "755", expected: 0755},
+			{permission: "0999", fails: true},
+			{permission: "01777", fails: true},
+			{permission: "rw-r--r--", fails: true},
+		}
+		for _, value := range testContent {
+			mode, err := ParsePermission(value.permission)
+			if value.fails {
+				assert.Error(t, err)
+				continue
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, value.expected, mode)
+		}
+	})
+}
+
+func TestLookupOwner(t *testing.T) {
+	t.Run("Accept numeric ids & leave empty values unset", func(t *testing.T) {
+		uid, gid, err := lookupOwner("1000", "")
+		assert.NoError(t, err)
+		assert.Equal(t, 1000, uid)
+		assert.Equal(t, -1, gid)
+
+		_, _, err = lookupOwner("user-that-does-not-exist", "")
+		assert.Error(t, err)
+	})
+}
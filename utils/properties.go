@@ -0,0 +1,290 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Java properties files (.properties) are handled as flat key/value files, like .env files. Items can be
+// provided using the properties syntax or as a JSON object, nested keys of the JSON object are joined
+// with dots (e.g. `{"spring":{"datasource":{"url":"..."}}}` is written as `spring.datasource.url=...`)
+// and arrays use the index notation (`hosts[0]=...`).
+
+type propertiesLine struct {
+	// raw holds the line as it is in the file, continuation lines included
+	raw string
+	// key is empty for comments and blank lines
+	key       string
+	value     string
+	indent    string
+	separator string
+}
+
+func isProperties(path string) bool {
+	return filepath.Ext(path) == ".properties"
+}
+
+func parseProperties(in []byte) []propertiesLine {
+	var lines []propertiesLine
+	natural := strings.Split(strings.ReplaceAll(string(in), "\r\n", "\n"), "\n")
+	if len(natural) > 0 && natural[len(natural)-1] == "" {
+		natural = natural[:len(natural)-1]
+	}
+	for i := 0; i < len(natural); i++ {
+		raw := natural[i]
+		text := strings.TrimLeft(raw, " \t\f")
+		if text == "" || text[0] == '#' || text[0] == '!' {
+			lines = append(lines, propertiesLine{raw: raw})
+			continue
+		}
+		// a line ending with an odd number of backslashes continues in the next line
+		for endsWithEscape(text) && i+1 < len(natural) {
+			i++
+			raw += "\n" + natural[i]
+			text = text[:len(text)-1] + strings.TrimLeft(natural[i], " \t\f")
+		}
+		if endsWithEscape(text) {
+			text = text[:len(text)-1]
+		}
+
+		keyEnd := 0
+		for keyEnd < len(text) {
+			c := text[keyEnd]
+			if c == '\\' {
+				keyEnd += 2
+				continue
+			}
+			if c == '=' || c == ':' || c == ' ' || c == '\t' || c == '\f' {
+				break
+			}
+			keyEnd++
+		}
+		if keyEnd > len(text) {
+			keyEnd = len(text)
+		}
+		valueStart := keyEnd
+		for valueStart < len(text) && strings.ContainsRune(" \t\f", rune(text[valueStart])) {
+			valueStart++
+		}
+		if valueStart < len(text) && (text[valueStart] == '=' || text[valueStart] == ':') {
+			valueStart++
+			for valueStart < len(text) && strings.ContainsRune(" \t\f", rune(text[valueStart])) {
+				valueStart++
+			}
+		}
+		lines = append(lines, propertiesLine{
+			raw:       raw,
+			key:       unescapeProperty(text[:keyEnd]),
+			value:     unescapeProperty(text[valueStart:]),
+			indent:    raw[:len(raw)-len(strings.TrimLeft(raw, " \t\f"))],
+			separator: text[keyEnd:valueStart],
+		})
+	}
+	return lines
+}
+
+func endsWithEscape(s string) bool {
+	n := 0
+	for i := len(s) - 1; i >= 0 && s[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+func unescapeProperty(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+4 < len(s) {
+				if r, err := strconv.ParseUint(s[i+1:i+5], 16, 16); err == nil {
+					// surrogate pairs are written as two escape sequences
+					if utf16.IsSurrogate(rune(r)) && i+10 < len(s) && s[i+5] == '\\' && s[i+6] == 'u' {
+						if r2, err := strconv.ParseUint(s[i+7:i+11], 16, 16); err == nil {
+							b.WriteRune(utf16.DecodeRune(rune(r), rune(r2)))
+							i += 10
+							continue
+						}
+					}
+					b.WriteRune(rune(r))
+					i += 4
+					continue
+				}
+			}
+			b.WriteByte('u')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func escapeProperty(s string, isKey bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r == ' ' && (isKey || i == 0):
+			b.WriteString(`\ `)
+		case isKey && strings.ContainsRune("=:#!", r):
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			// non ASCII characters are escaped, so the file can be read as ISO 8859-1 or UTF-8
+			for _, c := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&b, `\u%04X`, c)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// decodeProperties returns the properties defined in the file, when a key is defined
+// more than once the last value is the one returned (as it happens in Java)
+func decodeProperties(in []byte) map[string]string {
+	content := map[string]string{}
+	for _, l := range parseProperties(in) {
+		if l.key != "" {
+			content[l.key] = l.value
+		}
+	}
+	return content
+}
+
+func encodeProperties(content map[string]string) string {
+	keys := make([]string, 0, len(content))
+	for k := range content {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	lines := make([]string, 0, len(keys))
+	for _, k := range keys {
+		lines = append(lines, escapeProperty(k, true)+"="+escapeProperty(content[k], false))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// propertiesPatch writes the content reusing the layout of the original file: comments, blank lines, the
+// order of the properties and the lines whose value did not change are kept, removed properties are dropped
+// and new properties are appended at the end of the file
+func propertiesPatch(original []byte, content map[string]string) []byte {
+	var out []string
+	written := map[string]bool{}
+	for _, l := range parseProperties(original) {
+		if l.key == "" {
+			out = append(out, l.raw)
+			continue
+		}
+		v, ok := content[l.key]
+		if !ok {
+			continue
+		}
+		written[l.key] = true
+		if v == l.value {
+			out = append(out, l.raw)
+			continue
+		}
+		separator := l.separator
+		if separator == "" {
+			separator = "="
+		}
+		out = append(out, l.indent+escapeProperty(l.key, true)+separator+escapeProperty(v, false))
+	}
+	newContent := map[string]string{}
+	for k, v := range content {
+		if !written[k] {
+			newContent[k] = v
+		}
+	}
+	if len(newContent) > 0 {
+		out = append(out, encodeProperties(newContent))
+	}
+	if len(out) == 0 {
+		return []byte{}
+	}
+	return []byte(strings.Join(out, "\n") + "\n")
+}
+
+// parsePropertiesItems decodes items provided either as a JSON object or using the properties syntax
+func parsePropertiesItems(items string) (map[string]string, error) {
+	trimmed := strings.TrimSpace(items)
+	if !isJSONObject(trimmed) {
+		// items are usually defined with heredoc strings, so the indentation is removed
+		return decodeProperties([]byte(trimmed)), nil
+	}
+	content := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(trimmed), &content); err != nil {
+		return nil, err
+	}
+	properties := map[string]string{}
+	flattenKeys(content, "", ".", properties)
+	return properties, nil
+}
+
+// nullKeys collects the flattened keys of the content whose value is null
+func nullKeys(content map[string]interface{}, prefix, separator string, out *[]string) {
+	for k, v := range content {
+		key := k
+		if prefix != "" {
+			key = prefix + separator + k
+		}
+		switch value := v.(type) {
+		case nil:
+			*out = append(*out, key)
+		case map[string]interface{}:
+			nullKeys(value, key, separator, out)
+		}
+	}
+}
+
+// flattenKeys joins the nested keys of content with the given separator, array elements
+// are written using the index notation (`key[0]`)
+func flattenKeys(content interface{}, prefix, separator string, out map[string]string) {
+	switch value := content.(type) {
+	case map[string]interface{}:
+		for k, v := range value {
+			key := k
+			if prefix != "" {
+				key = prefix + separator + k
+			}
+			flattenKeys(v, key, separator, out)
+		}
+	case []interface{}:
+		for i, v := range value {
+			flattenKeys(v, fmt.Sprintf("%s[%d]", prefix, i), separator, out)
+		}
+	case nil:
+		out[prefix] = ""
+	default:
+		out[prefix] = stringValue(value)
+	}
+}
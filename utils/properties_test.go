@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeProperties(t *testing.T) {
+	t.Run("Decode separators, escapes & continuation lines", func(t *testing.T) {
+		in := `# database
+! legacy comment
+spring.datasource.url=jdbc:postgresql://localhost/app
+spring.datasource.username : app
+server.port 8080
+greeting=Hello \
+    World
+path=C:\\temp
+key\ with\ spaces=value
+unicode=caf\u00e9
+`
+		assert.Equal(t, map[string]string{
+			"spring.datasource.url":      "jdbc:postgresql://localhost/app",
+			"spring.datasource.username": "app",
+			"server.port":                "8080",
+			"greeting":                   "Hello World",
+			"path":                       `C:\temp`,
+			"key with spaces":            "value",
+			"unicode":                    "café",
+		}, decodeProperties([]byte(in)))
+	})
+}
+
+func TestEscapeProperty(t *testing.T) {
+	t.Run("Escape keys & values", func(t *testing.T) {
+		assert.Equal(t, `key\ with\=sep`, escapeProperty("key with=sep", true))
+		assert.Equal(t, `\ leading space=kept`, escapeProperty(" leading space=kept", false))
+		assert.Equal(t, `caf\u00E9\n`, escapeProperty("café\n", false))
+		assert.Equal(t, "café", unescapeProperty(escapeProperty("café", false)))
+		assert.Equal(t, "😀", unescapeProperty(escapeProperty("😀", false)))
+	})
+}
+
+func TestPropertiesPatch(t *testing.T) {
+	t.Run("Keep comments, separators & order", func(t *testing.T) {
+		original := "# server\nserver.port : 8080\nserver.host=localhost\n\n# removed\nlegacy=true\n"
+		content := map[string]string{
+			"server.port": "9090",
+			"server.host": "localhost",
+			"new.key":     "value",
+		}
+		assert.Equal(t, "# server\nserver.port : 9090\nserver.host=localhost\n\n# removed\nnew.key=value\n", string(propertiesPatch([]byte(original), content)))
+	})
+}
+
+func TestParsePropertiesItems(t *testing.T) {
+	t.Run("Flatten JSON items & decode properties items", func(t *testing.T) {
+		items, err := parsePropertiesItems(`{"spring":{"datasource":{"url":"jdbc:h2:mem"}},"hosts":["a","b"],"port":8080}`)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"spring.datasource.url": "jdbc:h2:mem",
+			"hosts[0]":              "a",
+			"hosts[1]":              "b",
+			"port":                  "8080",
+		}, items)
+
+		items, err = parsePropertiesItems("\n  spring.datasource.url=jdbc:h2:mem\n  port=8080\n")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"spring.datasource.url": "jdbc:h2:mem", "port": "8080"}, items)
+	})
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"encoding"
+	"math"
+	"path/filepath"
+	"reflect"
+)
+
+func isToml(path string) bool {
+	return filepath.Ext(path) == ".toml"
+}
+
+// tomlTypes restores the toml types of the values decoded from json, that is the values of items and the
+// previous values restored from the changes. Numbers without decimal part are converted to integers,
+// otherwise `port = 8080` would be written as `port = 8080.0`, unless the number placed in the same path
+// of original (the content of the toml file) is a float, so `ratio = 1.0` is not rewritten as `ratio = 1`.
+// Strings placed in the same path as a datetime of original are converted to datetimes
+func tomlTypes(v, original interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		originalMap, _ := original.(map[string]interface{})
+		c := make(map[string]interface{}, len(value))
+		for k, e := range value {
+			c[k] = tomlTypes(e, originalMap[k])
+		}
+		return c
+	case []interface{}:
+		originalSlice, _ := original.([]interface{})
+		c := make([]interface{}, len(value))
+		for i, e := range value {
+			var originalElement interface{}
+			if i < len(originalSlice) {
+				originalElement = originalSlice[i]
+			}
+			c[i] = tomlTypes(e, originalElement)
+		}
+		return c
+	case float64:
+		if _, ok := original.(float64); ok {
+			return v
+		}
+		if value == math.Trunc(value) && math.Abs(value) < 1<<53 {
+			return int64(value)
+		}
+	case string:
+		return tomlDatetime(value, original)
+	}
+	return v
+}
+
+// tomlDatetime parses the string as a datetime when original is a datetime (offset or local date-times,
+// dates and times), as the values restored from the changes are decoded from json they are written as
+// strings otherwise
+func tomlDatetime(value string, original interface{}) interface{} {
+	if original == nil {
+		return value
+	}
+	if _, ok := original.(string); ok {
+		return value
+	}
+	datetime := reflect.New(reflect.TypeOf(original))
+	unmarshaler, ok := datetime.Interface().(encoding.TextUnmarshaler)
+	if !ok || unmarshaler.UnmarshalText([]byte(value)) != nil {
+		return value
+	}
+	return datetime.Elem().Interface()
+}
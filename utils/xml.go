@@ -0,0 +1,439 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// XML documents are mapped to the map model used by Merge in the following way:
+//   - the document is a map with a single key, the name of the root element
+//   - an element without attributes and child elements is a string holding its text, empty elements are null
+//   - any other element is a map, attributes are stored with the `@` prefix (e.g. `@version`), child elements
+//     by their name and the text (when it isn't blank) in the `#text` key
+//   - child elements with the same name are stored as an array, in the order they are defined
+//
+// Namespace prefixes are kept as part of the names (e.g. `@xmlns:xsi`, `soap:Body`). When an existing file is
+// rewritten, comments, the order of elements and attributes and the elements that didn't change are kept,
+// new elements are appended at the end of their parent.
+
+const (
+	xmlAttrPrefix = "@"
+	xmlTextKey    = "#text"
+	xmlIndent     = "  "
+)
+
+// whitespace is kept as it is in text, so the indentation of the document isn't escaped
+var (
+	xmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	xmlAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "\n", "&#xA;", "\t", "&#x9;")
+)
+
+type xmlNode struct {
+	// name is empty for nodes that aren't elements (text, comments, processing instructions and directives)
+	name     string
+	attrs    []xml.Attr
+	children []*xmlNode
+	token    xml.Token
+}
+
+func init() {
+	supportedFileExtDecode[".xml"] = xmlUnmarshal
+	supportedFileExtEncode[".xml"] = xmlMarshal
+	supportedFileExtPatch[".xml"] = xmlPatch
+}
+
+func isXml(path string) bool {
+	return filepath.Ext(path) == ".xml"
+}
+
+// alignXmlArrays converts to arrays the elements of the file that are defined once when the same element
+// holds an array in items, as a single element is decoded as a value (not an array), so that elements can
+// be joined (or overridden) the same way as repeated elements
+func alignXmlArrays(src, dst map[string]interface{}) {
+	for k, srcValue := range src {
+		dstValue, ok := dst[k]
+		if !ok {
+			continue
+		}
+		switch srcValue.(type) {
+		case []interface{}:
+			if _, isArray := dstValue.([]interface{}); !isArray {
+				dst[k] = []interface{}{dstValue}
+			}
+		case map[string]interface{}:
+			if dstMap, ok := dstValue.(map[string]interface{}); ok {
+				alignXmlArrays(srcValue.(map[string]interface{}), dstMap)
+			}
+		}
+	}
+}
+
+func xmlName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+// parseXML returns the document node, its children are the nodes of the prolog, the root element
+// and the nodes defined after it
+func parseXML(in []byte) (*xmlNode, error) {
+	doc := &xmlNode{}
+	stack := []*xmlNode{doc}
+	d := xml.NewDecoder(bytes.NewReader(in))
+	for {
+		token, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		parent := stack[len(stack)-1]
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: xmlName(t.Name)}
+			for _, a := range t.Attr {
+				node.attrs = append(node.attrs, xml.Attr{Name: xml.Name{Local: xmlName(a.Name)}, Value: a.Value})
+			}
+			parent.children = append(parent.children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) == 1 || parent.name != xmlName(t.Name) {
+				return nil, fmt.Errorf("unexpected end element </%s>", xmlName(t.Name))
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			parent.children = append(parent.children, &xmlNode{token: xml.CopyToken(token)})
+		}
+	}
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("element <%s> is not closed", stack[len(stack)-1].name)
+	}
+	if xmlRoot(doc) == nil {
+		return nil, errors.New("XML document has no root element")
+	}
+	return doc, nil
+}
+
+func xmlRoot(doc *xmlNode) *xmlNode {
+	for _, c := range doc.children {
+		if c.name != "" {
+			return c
+		}
+	}
+	return nil
+}
+
+// xmlValue returns the value of the element in the map model
+func xmlValue(node *xmlNode) interface{} {
+	var text strings.Builder
+	value := map[string]interface{}{}
+	for _, a := range node.attrs {
+		value[xmlAttrPrefix+a.Name.Local] = a.Value
+	}
+	for _, c := range node.children {
+		if c.name == "" {
+			if data, ok := c.token.(xml.CharData); ok {
+				text.Write(data)
+			}
+			continue
+		}
+		switch v := value[c.name].(type) {
+		case nil:
+			value[c.name] = xmlValue(c)
+		case []interface{}:
+			value[c.name] = append(v, xmlValue(c))
+		default:
+			value[c.name] = []interface{}{v, xmlValue(c)}
+		}
+	}
+	t := strings.TrimSpace(text.String())
+	if len(value) == 0 && t == "" && len(node.children) == 0 {
+		return nil
+	}
+	if len(value) == 0 {
+		return t
+	}
+	if t != "" {
+		value[xmlTextKey] = t
+	}
+	return value
+}
+
+func xmlUnmarshal(in []byte, out interface{}) error {
+	content, ok := out.(*map[string]interface{})
+	if !ok {
+		return errors.New("XML content can only be decoded to map[string]interface{}")
+	}
+	doc, err := parseXML(in)
+	if err != nil {
+		return err
+	}
+	if *content == nil {
+		*content = map[string]interface{}{}
+	}
+	root := xmlRoot(doc)
+	(*content)[root.name] = xmlValue(root)
+	return nil
+}
+
+func xmlMarshal(in interface{}) ([]byte, error) {
+	return xmlPatch(nil, in)
+}
+
+// xmlPatch writes the content reusing the original document, the elements whose value didn't change are
+// written as they are and comments, processing instructions and the order of the elements are kept
+func xmlPatch(original []byte, in interface{}) ([]byte, error) {
+	content, ok := in.(map[string]interface{})
+	if !ok || len(content) != 1 {
+		return nil, errors.New("XML content must be an object with a single key, the name of the root element")
+	}
+	var rootName string
+	for k := range content {
+		rootName = k
+	}
+
+	doc := &xmlNode{children: []*xmlNode{
+		{token: xml.ProcInst{Target: "xml", Inst: []byte(`version="1.0" encoding="UTF-8"`)}},
+		{token: xml.CharData("\n")},
+	}}
+	if len(original) > 0 {
+		var err error
+		if doc, err = parseXML(original); err != nil {
+			return nil, err
+		}
+	}
+	indent := xmlDetectIndent(doc)
+
+	var root *xmlNode
+	for i, c := range doc.children {
+		if c.name != "" {
+			root = c
+			if c.name != rootName {
+				root = &xmlNode{name: rootName}
+				doc.children[i] = root
+			}
+			break
+		}
+	}
+	if root == nil {
+		root = &xmlNode{name: rootName}
+		doc.children = append(doc.children, root, &xmlNode{token: xml.CharData("\n")})
+	}
+	if err := xmlReconcile(root, content[rootName], 0, indent); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	for _, c := range doc.children {
+		if err := xmlWrite(&b, c); err != nil {
+			return nil, err
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// xmlDetectIndent returns the indentation used by the first child element of the root element
+func xmlDetectIndent(doc *xmlNode) string {
+	root := xmlRoot(doc)
+	if root == nil {
+		return xmlIndent
+	}
+	for _, c := range root.children {
+		if data, ok := c.token.(xml.CharData); ok && strings.Contains(string(data), "\n") {
+			ws := string(data)
+			if indent := ws[strings.LastIndex(ws, "\n")+1:]; indent != "" && strings.TrimSpace(indent) == "" {
+				return indent
+			}
+		}
+	}
+	return xmlIndent
+}
+
+// xmlReconcile updates the element so that its value is equal to value, depth is used
+// to indent the elements that are added
+func xmlReconcile(node *xmlNode, value interface{}, depth int, indent string) error {
+	if jsonEqual(xmlValue(node), value) {
+		return nil
+	}
+	content, isMap := value.(map[string]interface{})
+	if !isMap {
+		if _, isSlice := value.([]interface{}); isSlice {
+			return fmt.Errorf("Value of element <%s> can't be an array", node.name)
+		}
+		node.attrs = nil
+		node.children = nil
+		if value != nil {
+			node.children = []*xmlNode{{token: xml.CharData(stringValue(value))}}
+		}
+		return nil
+	}
+
+	// attributes keep their order, new attributes are appended
+	var attrs []xml.Attr
+	for _, a := range node.attrs {
+		if v, ok := content[xmlAttrPrefix+a.Name.Local]; ok {
+			attrs = append(attrs, xml.Attr{Name: a.Name, Value: xmlText(v)})
+		}
+	}
+	for _, k := range sortedKeys(content) {
+		if !strings.HasPrefix(k, xmlAttrPrefix) || xmlHasAttr(node, k[len(xmlAttrPrefix):]) {
+			continue
+		}
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: k[len(xmlAttrPrefix):]}, Value: xmlText(content[k])})
+	}
+	node.attrs = attrs
+
+	// child elements are matched by name and position, elements that are no longer part of the value
+	// are removed together with the whitespace that precedes them
+	var children []*xmlNode
+	occurrences := map[string]int{}
+	textWritten := false
+	lastElement := -1
+	for _, c := range node.children {
+		if c.name == "" {
+			if data, ok := c.token.(xml.CharData); ok && strings.TrimSpace(string(data)) != "" {
+				text, hasText := content[xmlTextKey]
+				if !hasText || textWritten {
+					continue
+				}
+				textWritten = true
+				c = &xmlNode{token: xml.CharData(xmlText(text))}
+			}
+			children = append(children, c)
+			continue
+		}
+		values := xmlElementValues(content, c.name)
+		i := occurrences[c.name]
+		occurrences[c.name]++
+		if i >= len(values) {
+			if len(children) > 0 && xmlIsWhitespace(children[len(children)-1]) {
+				children = children[:len(children)-1]
+			}
+			continue
+		}
+		if err := xmlReconcile(c, values[i], depth+1, indent); err != nil {
+			return err
+		}
+		children = append(children, c)
+		lastElement = len(children) - 1
+	}
+	if text, ok := content[xmlTextKey]; ok && !textWritten {
+		children = append([]*xmlNode{{token: xml.CharData(xmlText(text))}}, children...)
+		if lastElement >= 0 {
+			lastElement++
+		}
+	}
+
+	// new elements are appended after the last element of the parent
+	var newChildren []*xmlNode
+	for _, k := range sortedKeys(content) {
+		if strings.HasPrefix(k, xmlAttrPrefix) || k == xmlTextKey {
+			continue
+		}
+		values := xmlElementValues(content, k)
+		for i := occurrences[k]; i < len(values); i++ {
+			child := &xmlNode{name: k}
+			if err := xmlReconcile(child, values[i], depth+1, indent); err != nil {
+				return err
+			}
+			newChildren = append(newChildren, &xmlNode{token: xml.CharData("\n" + strings.Repeat(indent, depth+1))}, child)
+		}
+	}
+	if len(newChildren) > 0 {
+		if lastElement >= 0 {
+			children = append(children[:lastElement+1], append(newChildren, children[lastElement+1:]...)...)
+		} else {
+			var kept []*xmlNode
+			for _, c := range children {
+				if !xmlIsWhitespace(c) {
+					kept = append(kept, c)
+				}
+			}
+			children = append(append(kept, newChildren...), &xmlNode{token: xml.CharData("\n" + strings.Repeat(indent, depth))})
+		}
+	}
+	node.children = children
+	return nil
+}
+
+// xmlElementValues returns the values of the child elements with the given name,
+// a null value is written as an empty element
+func xmlElementValues(content map[string]interface{}, name string) []interface{} {
+	v, ok := content[name]
+	if !ok {
+		return nil
+	}
+	if values, isSlice := v.([]interface{}); isSlice {
+		return values
+	}
+	return []interface{}{v}
+}
+
+func xmlHasAttr(node *xmlNode, name string) bool {
+	for _, a := range node.attrs {
+		if a.Name.Local == name {
+			return true
+		}
+	}
+	return false
+}
+
+func xmlIsWhitespace(node *xmlNode) bool {
+	data, ok := node.token.(xml.CharData)
+	return ok && strings.TrimSpace(string(data)) == ""
+}
+
+func xmlText(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return stringValue(v)
+}
+
+func xmlWrite(b *bytes.Buffer, node *xmlNode) error {
+	if node.name == "" {
+		switch t := node.token.(type) {
+		case xml.CharData:
+			b.WriteString(xmlTextEscaper.Replace(string(t)))
+		case xml.Comment:
+			b.WriteString("<!--")
+			b.Write(t)
+			b.WriteString("-->")
+		case xml.ProcInst:
+			b.WriteString("<?" + t.Target)
+			if len(t.Inst) > 0 {
+				b.WriteString(" ")
+				b.Write(t.Inst)
+			}
+			b.WriteString("?>")
+		case xml.Directive:
+			b.WriteString("<!")
+			b.Write(t)
+			b.WriteString(">")
+		}
+		return nil
+	}
+	b.WriteString("<" + node.name)
+	for _, a := range node.attrs {
+		b.WriteString(" " + a.Name.Local + `="` + xmlAttrEscaper.Replace(a.Value) + `"`)
+	}
+	if len(node.children) == 0 {
+		b.WriteString("/>")
+		return nil
+	}
+	b.WriteString(">")
+	for _, c := range node.children {
+		if err := xmlWrite(b, c); err != nil {
+			return err
+		}
+	}
+	b.WriteString("</" + node.name + ">")
+	return nil
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXmlUnmarshal(t *testing.T) {
+	t.Run("Map elements, attributes & text to the map model", func(t *testing.T) {
+		in := `<?xml version="1.0" encoding="UTF-8"?>
+<Server port="8005" shutdown="SHUTDOWN">
+  <!-- listeners -->
+  <Listener className="org.apache.catalina.startup.VersionLoggerListener"/>
+  <Service name="Catalina">
+    <Connector port="8080" protocol="HTTP/1.1"/>
+    <Connector port="8443" protocol="HTTP/1.1">secure</Connector>
+    <Engine name="Catalina" defaultHost="localhost"/>
+  </Service>
+  <Description>Tomcat</Description>
+  <Empty/>
+</Server>
+`
+		content := map[string]interface{}{}
+		err := xmlUnmarshal([]byte(in), &content)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"Server": map[string]interface{}{
+				"@port":     "8005",
+				"@shutdown": "SHUTDOWN",
+				"Listener":  map[string]interface{}{"@className": "org.apache.catalina.startup.VersionLoggerListener"},
+				"Service": map[string]interface{}{
+					"@name": "Catalina",
+					"Connector": []interface{}{
+						map[string]interface{}{"@port": "8080", "@protocol": "HTTP/1.1"},
+						map[string]interface{}{"@port": "8443", "@protocol": "HTTP/1.1", "#text": "secure"},
+					},
+					"Engine": map[string]interface{}{"@name": "Catalina", "@defaultHost": "localhost"},
+				},
+				"Description": "Tomcat",
+				"Empty":       nil,
+			},
+		}, content)
+	})
+	t.Run("Return error when document is malformed", func(t *testing.T) {
+		content := map[string]interface{}{}
+		err := xmlUnmarshal([]byte(`<project><name>demo</project>`), &content)
+		assert.Error(t, err)
+	})
+}
+
+func TestXmlPatch(t *testing.T) {
+	t.Run("Keep comments, order & untouched elements", func(t *testing.T) {
+		original := `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+    <modelVersion>4.0.0</modelVersion>
+    <artifactId>demo</artifactId>
+    <!-- dependencies -->
+    <dependencies>
+        <dependency>
+            <groupId>junit</groupId>
+        </dependency>
+    </dependencies>
+</project>
+`
+		content := map[string]interface{}{
+			"project": map[string]interface{}{
+				"@xmlns":       "http://maven.apache.org/POM/4.0.0",
+				"modelVersion": "4.0.0",
+				"dependencies": map[string]interface{}{
+					"dependency": []interface{}{
+						map[string]interface{}{"groupId": "junit"},
+						map[string]interface{}{"groupId": "log4j", "@optional": true},
+					},
+				},
+				"properties": map[string]interface{}{"java.version": float64(17)},
+			},
+		}
+		expected := `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+    <modelVersion>4.0.0</modelVersion>
+    <!-- dependencies -->
+    <dependencies>
+        <dependency>
+            <groupId>junit</groupId>
+        </dependency>
+        <dependency optional="true">
+            <groupId>log4j</groupId>
+        </dependency>
+    </dependencies>
+    <properties>
+        <java.version>17</java.version>
+    </properties>
+</project>
+`
+		out, err := xmlPatch([]byte(original), content)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(out))
+	})
+	t.Run("Create document from content", func(t *testing.T) {
+		out, err := xmlMarshal(map[string]interface{}{
+			"note": map[string]interface{}{"@lang": "en", "to": "Tove & Jani", "body": nil},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<note lang=\"en\">\n  <body/>\n  <to>Tove &amp; Jani</to>\n</note>\n", string(out))
+	})
+	t.Run("Return error when content has more than one root element", func(t *testing.T) {
+		_, err := xmlMarshal(map[string]interface{}{"a": "1", "b": "2"})
+		assert.ErrorContains(t, err, "single key")
+	})
+}
@@ -0,0 +1,507 @@
+package utils
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	supportedFileExtPatch[".yaml"] = yamlPatch
+	supportedFileExtPatch[".yml"] = yamlPatch
+}
+
+// yamlPatch writes the content editing the original document in place: only the entries (keys and sequence
+// items) whose value changed are written again, reusing their nodes so their comments, quoting and block
+// styles are kept, the rest of the file (blank lines, indentation, comments, anchors and aliases, and the
+// documents following the first one) is left as it is. Removed entries are dropped and new entries are
+// appended at the end of their mapping or sequence
+func yamlPatch(original []byte, in interface{}) ([]byte, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(original, &document); err != nil {
+		return nil, err
+	}
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 {
+		// files holding only comments have no document to patch
+		return yaml.Marshal(in)
+	}
+	root := document.Content[0]
+	if yamlEqual(root, in) {
+		return original, nil
+	}
+
+	p := yamlPatcher{lines: strings.Split(string(original), "\n"), indent: yamlIndent(original), crlf: bytes.Contains(original, []byte("\r\n"))}
+	start := yamlPosition{line: root.Line - 1, column: root.Column - 1}
+	end := p.trimEnd(start, p.documentEnd(start.line))
+	ok, err := p.patch(root, in, end)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// the whole document is written again, the comments placed before it are kept
+		reconciled, err := yamlReconcile(root, in)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.replace(start, end, yamlBare(reconciled)); err != nil {
+			return nil, err
+		}
+	}
+	return []byte(p.apply()), nil
+}
+
+// yamlPosition is the position of a node in the file, both the line and the column start at 0
+type yamlPosition struct {
+	line, column int
+}
+
+// yamlEdit replaces the lines of the file from start to end (excluded)
+type yamlEdit struct {
+	start, end int
+	lines      []string
+}
+
+// yamlPatcher collects the edits made to the lines of a yaml file, entries are delimited by the line of their key
+// (or the dash of sequence items) and the line of the entry that follows them
+type yamlPatcher struct {
+	lines  []string
+	indent int
+	crlf   bool
+	edits  []yamlEdit
+}
+
+// patch edits the entries of the block mapping or sequence to hold the value, false is returned when the node
+// can't be edited in place (e.g. flow collections or a value of another type) so it must be written again
+func (p *yamlPatcher) patch(node *yaml.Node, value interface{}, end int) (bool, error) {
+	edits := len(p.edits)
+	ok, err := false, error(nil)
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if node.Kind == yaml.MappingNode && node.Style&yaml.FlowStyle == 0 && len(v) > 0 {
+			ok, err = p.patchMapping(node, v, end)
+		}
+	case []interface{}:
+		if node.Kind == yaml.SequenceNode && node.Style&yaml.FlowStyle == 0 && len(v) > 0 {
+			ok, err = p.patchSequence(node, v, end)
+		}
+	}
+	if !ok {
+		p.edits = p.edits[:edits]
+	}
+	return ok, err
+}
+
+func (p *yamlPatcher) patchMapping(node *yaml.Node, value map[string]interface{}, end int) (bool, error) {
+	// keys provided by merge keys (`<<: *anchor`) are kept in the anchor unless they were changed
+	merged := map[string]interface{}{}
+	node.Decode(&merged)
+
+	defined := map[string]bool{}
+	last := end
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, keyValue := node.Content[i], node.Content[i+1]
+		if key.Kind != yaml.ScalarNode {
+			return false, nil
+		}
+		start := yamlPosition{line: key.Line - 1, column: key.Column - 1}
+		last = end
+		if i+2 < len(node.Content) {
+			last = node.Content[i+2].Line - 1
+		}
+		last = p.trimEnd(start, last)
+		if key.Tag == "!!merge" {
+			continue
+		}
+		v, ok := value[key.Value]
+		if !ok {
+			if !p.remove(start, last, key) {
+				return false, nil
+			}
+			continue
+		}
+		defined[key.Value] = true
+		if yamlEqual(keyValue, v) {
+			continue
+		}
+		if ok, err := p.patch(keyValue, v, last); err != nil || ok {
+			if err != nil {
+				return false, err
+			}
+			continue
+		}
+		n, err := yamlReconcile(keyValue, v)
+		if err != nil {
+			return false, err
+		}
+		entry := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{yamlBare(key), yamlBare(n)}}
+		if err := p.replace(start, last, entry); err != nil {
+			return false, err
+		}
+	}
+
+	added := &yaml.Node{Kind: yaml.MappingNode}
+	for _, k := range sortedKeys(value) {
+		if defined[k] {
+			continue
+		}
+		if mergedValue, ok := merged[k]; ok && jsonEqual(mergedValue, value[k]) {
+			continue
+		}
+		n, err := yamlNode(value[k])
+		if err != nil {
+			return false, err
+		}
+		added.Content = append(added.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}, n)
+	}
+	if len(added.Content) > 0 {
+		return true, p.insert(last, node.Content[0].Column-1, added)
+	}
+	return true, nil
+}
+
+func (p *yamlPatcher) patchSequence(node *yaml.Node, value []interface{}, end int) (bool, error) {
+	last, column := end, 0
+	for i, item := range node.Content {
+		start, ok := p.dash(item)
+		if !ok {
+			return false, nil
+		}
+		last, column = end, start.column
+		if i+1 < len(node.Content) {
+			next, ok := p.dash(node.Content[i+1])
+			if !ok {
+				return false, nil
+			}
+			last = next.line
+		}
+		last = p.trimEnd(start, last)
+		if i >= len(value) {
+			if !p.remove(start, last, item) {
+				return false, nil
+			}
+			continue
+		}
+		if yamlEqual(item, value[i]) {
+			continue
+		}
+		if ok, err := p.patch(item, value[i], last); err != nil || ok {
+			if err != nil {
+				return false, err
+			}
+			continue
+		}
+		n, err := yamlReconcile(item, value[i])
+		if err != nil {
+			return false, err
+		}
+		if err := p.replace(start, last, &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{yamlBare(n)}}); err != nil {
+			return false, err
+		}
+	}
+
+	added := &yaml.Node{Kind: yaml.SequenceNode}
+	for i := len(node.Content); i < len(value); i++ {
+		n, err := yamlNode(value[i])
+		if err != nil {
+			return false, err
+		}
+		added.Content = append(added.Content, n)
+	}
+	if len(added.Content) > 0 {
+		return true, p.insert(last, column, added)
+	}
+	return true, nil
+}
+
+// dash returns the position of the dash of the sequence item, it's the first character before the item other
+// than a space
+func (p *yamlPatcher) dash(item *yaml.Node) (yamlPosition, bool) {
+	if item.Line < 1 || item.Line > len(p.lines) {
+		return yamlPosition{}, false
+	}
+	line := []rune(p.lines[item.Line-1])
+	for column := item.Column - 2; column >= 0 && column < len(line); column-- {
+		if line[column] == ' ' {
+			continue
+		}
+		if line[column] == '-' {
+			return yamlPosition{line: item.Line - 1, column: column}, true
+		}
+		break
+	}
+	return yamlPosition{}, false
+}
+
+// trimEnd excludes the blank lines and the comments that are not indented below the entry from the end of
+// the entry, they belong to the entry that follows it
+func (p *yamlPatcher) trimEnd(start yamlPosition, end int) int {
+	for end > start.line+1 {
+		line := p.lines[end-1]
+		text := strings.TrimSpace(line)
+		if text != "" && (!strings.HasPrefix(text, "#") || len(line)-len(strings.TrimLeft(line, " ")) > start.column) {
+			break
+		}
+		end--
+	}
+	return end
+}
+
+// documentEnd returns the line of the document marker (`---` or `...`) that follows the line, or the number of
+// lines of the file when the document is the last one
+func (p *yamlPatcher) documentEnd(line int) int {
+	for i := line + 1; i < len(p.lines); i++ {
+		if yamlDocumentMarker(p.lines[i]) {
+			return i
+		}
+	}
+	return len(p.lines)
+}
+
+// remove drops the entry and its head comment, entries sharing their line with their parent (e.g. the first key
+// of a mapping placed in a sequence item) can't be removed on their own
+func (p *yamlPatcher) remove(start yamlPosition, end int, node *yaml.Node) bool {
+	prefix := []rune(p.lines[start.line])
+	if start.column > len(prefix) || strings.TrimSpace(string(prefix[:start.column])) != "" {
+		return false
+	}
+	first := start.line
+	if node.HeadComment != "" {
+		for n := strings.Count(strings.TrimSpace(node.HeadComment), "\n") + 1; n > 0 && first > 0; n-- {
+			if !strings.HasPrefix(strings.TrimSpace(p.lines[first-1]), "#") {
+				break
+			}
+			first--
+		}
+	}
+	p.edits = append(p.edits, yamlEdit{start: first, end: end})
+	return true
+}
+
+// replace writes the entry again, in the same column, the beginning of its first line is kept (e.g. the dash
+// of the sequence item the entry belongs to)
+func (p *yamlPatcher) replace(start yamlPosition, end int, node *yaml.Node) error {
+	lines, err := p.render(node)
+	if err != nil {
+		return err
+	}
+	prefix := []rune(p.lines[start.line])
+	if start.column < len(prefix) {
+		prefix = prefix[:start.column]
+	}
+	indentation := strings.Repeat(" ", start.column)
+	for i := range lines {
+		if i == 0 {
+			lines[i] = string(prefix) + lines[i]
+		} else if lines[i] != "" {
+			lines[i] = indentation + lines[i]
+		}
+	}
+	p.edits = append(p.edits, yamlEdit{start: start.line, end: end, lines: lines})
+	return nil
+}
+
+// insert writes the entries before the line, in the given column
+func (p *yamlPatcher) insert(line, column int, node *yaml.Node) error {
+	lines, err := p.render(node)
+	if err != nil {
+		return err
+	}
+	indentation := strings.Repeat(" ", column)
+	for i := range lines {
+		if lines[i] != "" {
+			lines[i] = indentation + lines[i]
+		}
+	}
+	p.edits = append(p.edits, yamlEdit{start: line, end: line, lines: lines})
+	return nil
+}
+
+func (p *yamlPatcher) render(node *yaml.Node) ([]string, error) {
+	yamlImplicitMergeKeys(node)
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(p.indent)
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if p.crlf {
+		// files written on windows keep their line endings
+		for i := range lines {
+			lines[i] += "\r"
+		}
+	}
+	return lines, nil
+}
+
+// apply returns the content of the file once edited, edits are applied from the end of the file so the lines
+// of the edits that are not applied yet don't move
+func (p *yamlPatcher) apply() string {
+	sort.SliceStable(p.edits, func(i, j int) bool {
+		if p.edits[i].start != p.edits[j].start {
+			return p.edits[i].start > p.edits[j].start
+		}
+		return p.edits[i].end > p.edits[j].end
+	})
+	lines := p.lines
+	for _, edit := range p.edits {
+		edited := make([]string, 0, len(lines)-(edit.end-edit.start)+len(edit.lines))
+		edited = append(append(append(edited, lines[:edit.start]...), edit.lines...), lines[edit.end:]...)
+		lines = edited
+	}
+	return strings.Join(lines, "\n")
+}
+
+func yamlDocumentMarker(line string) bool {
+	line = strings.TrimRight(line, "\r")
+	if !strings.HasPrefix(line, "---") && !strings.HasPrefix(line, "...") {
+		return false
+	}
+	return len(line) == 3 || line[3] == ' ' || line[3] == '\t'
+}
+
+func yamlEqual(node *yaml.Node, value interface{}) bool {
+	var current interface{}
+	return node.Decode(&current) == nil && jsonEqual(current, value)
+}
+
+// yamlBare returns a copy of the node without its head and foot comments, as they are placed outside of the
+// lines of the entry they are kept where they are
+func yamlBare(node *yaml.Node) *yaml.Node {
+	bare := *node
+	bare.HeadComment, bare.FootComment = "", ""
+	return &bare
+}
+
+// yamlReconcile returns the node that holds the given value, reusing the original node (or the parts of it)
+// whose value is the same
+func yamlReconcile(node *yaml.Node, value interface{}) (*yaml.Node, error) {
+	if yamlEqual(node, value) {
+		return node, nil
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if node.Kind == yaml.MappingNode {
+			return yamlReconcileMapping(node, v)
+		}
+	case []interface{}:
+		if node.Kind == yaml.SequenceNode {
+			return yamlReconcileSequence(node, v)
+		}
+	}
+
+	replacement, err := yamlNode(value)
+	if err != nil {
+		return nil, err
+	}
+	// the comments of the replaced node are kept, as well as the quoting style of strings
+	replacement.HeadComment, replacement.LineComment, replacement.FootComment = node.HeadComment, node.LineComment, node.FootComment
+	if node.Kind == yaml.ScalarNode && replacement.Kind == yaml.ScalarNode && node.Tag == replacement.Tag {
+		replacement.Style = node.Style
+	}
+	return replacement, nil
+}
+
+func yamlReconcileMapping(node *yaml.Node, value map[string]interface{}) (*yaml.Node, error) {
+	// keys provided by merge keys (`<<: *anchor`) are kept in the anchor unless they were changed
+	merged := map[string]interface{}{}
+	node.Decode(&merged)
+
+	reconciled := *node
+	reconciled.Content = nil
+	defined := map[string]bool{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, keyValue := node.Content[i], node.Content[i+1]
+		if key.Tag == "!!merge" {
+			reconciled.Content = append(reconciled.Content, key, keyValue)
+			continue
+		}
+		v, ok := value[key.Value]
+		if !ok {
+			continue
+		}
+		defined[key.Value] = true
+		n, err := yamlReconcile(keyValue, v)
+		if err != nil {
+			return nil, err
+		}
+		reconciled.Content = append(reconciled.Content, key, n)
+	}
+
+	for _, k := range sortedKeys(value) {
+		if defined[k] {
+			continue
+		}
+		if mergedValue, ok := merged[k]; ok && jsonEqual(mergedValue, value[k]) {
+			continue
+		}
+		n, err := yamlNode(value[k])
+		if err != nil {
+			return nil, err
+		}
+		reconciled.Content = append(reconciled.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}, n)
+	}
+	return &reconciled, nil
+}
+
+func yamlReconcileSequence(node *yaml.Node, value []interface{}) (*yaml.Node, error) {
+	reconciled := *node
+	reconciled.Content = nil
+	for i, v := range value {
+		var n *yaml.Node
+		var err error
+		if i < len(node.Content) {
+			n, err = yamlReconcile(node.Content[i], v)
+		} else {
+			n, err = yamlNode(v)
+		}
+		if err != nil {
+			return nil, err
+		}
+		reconciled.Content = append(reconciled.Content, n)
+	}
+	return &reconciled, nil
+}
+
+// yamlImplicitMergeKeys removes the tag of merge keys, the encoder would write them as `!!merge <<` otherwise
+func yamlImplicitMergeKeys(node *yaml.Node) {
+	for i, n := range node.Content {
+		if node.Kind == yaml.MappingNode && i%2 == 0 && n.Tag == "!!merge" {
+			n.Tag = ""
+		}
+		yamlImplicitMergeKeys(n)
+	}
+}
+
+func yamlNode(value interface{}) (*yaml.Node, error) {
+	var n yaml.Node
+	if err := n.Encode(value); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// yamlIndent returns the indentation used by the file, that is the smallest indentation of its lines,
+// files without indented lines are written with the default indentation of the encoder
+func yamlIndent(in []byte) int {
+	indent := 0
+	for _, line := range strings.Split(string(in), "\n") {
+		text := strings.TrimLeft(line, " ")
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if n := len(line) - len(text); n > 0 && (indent == 0 || n < indent) {
+			indent = n
+		}
+	}
+	if indent < 2 {
+		return 4
+	}
+	return indent
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestYamlPatch(t *testing.T) {
+	t.Run("Keep comments, order, quoting & anchors", func(t *testing.T) {
+		original := `# compose file
+version: "3.8"
+x-common: &common
+  restart: always
+  image: 'nginx:1.25'
+services:
+  web:
+    <<: *common
+    ports:
+      - "80:80" # public
+  db:
+    image: postgres # pinned
+    volumes: [data]
+`
+		content, err := decodeFile([]byte(original), "docker-compose.yml")
+		assert.NoError(t, err)
+		services := content["services"].(map[string]interface{})
+		services["web"].(map[string]interface{})["ports"] = []interface{}{"80:80", "443:443"}
+		services["db"].(map[string]interface{})["image"] = "postgres:16"
+		delete(services["db"].(map[string]interface{}), "volumes")
+		services["cache"] = map[string]interface{}{"image": "redis"}
+
+		out, err := yamlPatch([]byte(original), content)
+		assert.NoError(t, err)
+		assert.Equal(t, `# compose file
+version: "3.8"
+x-common: &common
+  restart: always
+  image: 'nginx:1.25'
+services:
+  web:
+    <<: *common
+    ports:
+      - "80:80" # public
+      - 443:443
+  db:
+    image: postgres:16 # pinned
+  cache:
+    image: redis
+`, string(out))
+	})
+	t.Run("Replace aliases whose value changed", func(t *testing.T) {
+		original := "base: &base\n  level: info\nworker:\n  logging: *base\n"
+		content := map[string]interface{}{
+			"base":   map[string]interface{}{"level": "info"},
+			"worker": map[string]interface{}{"logging": map[string]interface{}{"level": "debug"}},
+		}
+		out, err := yamlPatch([]byte(original), content)
+		assert.NoError(t, err)
+		assert.Equal(t, "base: &base\n  level: info\nworker:\n  logging:\n    level: debug\n", string(out))
+	})
+	t.Run("Keep compact sequences & blank lines", func(t *testing.T) {
+		original := `spec:
+  containers:
+  - name: api
+    image: api:v1   # pinned
+
+  - name: worker
+    image: worker:v1
+    args: [--verbose]
+
+  volumes:
+  - data
+`
+		content, err := decodeFile([]byte(original), "deployment.yaml")
+		assert.NoError(t, err)
+		spec := content["spec"].(map[string]interface{})
+		containers := spec["containers"].([]interface{})
+		containers[0].(map[string]interface{})["image"] = "api:v2"
+		delete(containers[1].(map[string]interface{}), "args")
+		spec["containers"] = append(containers, map[string]interface{}{"name": "cache"})
+		spec["volumes"] = []interface{}{}
+
+		out, err := yamlPatch([]byte(original), content)
+		assert.NoError(t, err)
+		assert.Equal(t, `spec:
+  containers:
+  - name: api
+    image: api:v2 # pinned
+
+  - name: worker
+    image: worker:v1
+  - name: cache
+
+  volumes: []
+`, string(out))
+	})
+	t.Run("Keep the documents that follow the first one", func(t *testing.T) {
+		original := "---\n# api\nname: api\nreplicas: 1   # scaled by hpa\n\n---\nname: worker\nreplicas: 1\n"
+		content := map[string]interface{}{"name": "api", "replicas": 1, "port": 8080}
+		out, err := yamlPatch([]byte(original), content)
+		assert.NoError(t, err)
+		assert.Equal(t, "---\n# api\nname: api\nreplicas: 1   # scaled by hpa\nport: 8080\n\n---\nname: worker\nreplicas: 1\n", string(out))
+
+		out, err = yamlPatch([]byte(original), []interface{}{"api"})
+		assert.NoError(t, err)
+		assert.Equal(t, "---\n# api\n- api\n\n---\nname: worker\nreplicas: 1\n", string(out))
+	})
+	t.Run("Keep the line endings of the file", func(t *testing.T) {
+		out, err := yamlPatch([]byte("a: 1\r\nb: 2\r\n"), map[string]interface{}{"a": 1, "b": 3})
+		assert.NoError(t, err)
+		assert.Equal(t, "a: 1\r\nb: 3\r\n", string(out))
+	})
+}
+
+func TestYamlIndent(t *testing.T) {
+	t.Run("Detect indentation of the file", func(t *testing.T) {
+		assert.Equal(t, 2, yamlIndent([]byte("a:\n  b:\n    c: 1\n")))
+		assert.Equal(t, 4, yamlIndent([]byte("a:\n    b: 1\n")))
+		assert.Equal(t, 4, yamlIndent([]byte("a: 1\n")))
+	})
+}